@@ -0,0 +1,627 @@
+// config.go
+// This file loads the runtime configuration for the receipt processing service.
+// Every setting has a default that preserves the original behavior, and can be
+// overridden through an environment variable.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// Config holds all runtime settings for the service.
+type Config struct {
+	Rules          RuleConfig        // Parameters for the point calculation rules
+	FieldAliases   map[string]string // Inbound JSON field names mapped to their canonical receipt field names
+	Envelope       bool              // Wrap response bodies in a {data, error, meta} envelope
+	PointsAsString bool              // Encode the points of /receipts/{id}/points as a quoted string, for clients without 64-bit integers
+
+	MaxItemPriceCents int64 // Highest accepted price for a single item, in cents; zero disables the check
+
+	ValidationChecks map[string]string // Severity ("error", "warning" or "off") of each data-quality check, by name
+
+	ReservedRetailers            []string // Trademarks that may not appear in retailer names
+	ReservedRetailerAllowedUsers []string // JWT subjects permitted to submit reserved retailer names
+
+	DefaultRetailer string // Retailer filled in when a receipt omits one; empty keeps the field required
+
+	MaxReceiptsPerRetailerPerDay int // Receipts a user may submit from one retailer per purchase date; zero disables the limit
+
+	AllowPurchaseTimeSeconds bool   // Accept HH:MM:SS purchase times, truncating them to HH:MM
+	AllowMissingLeadingZero  bool   // Accept amounts such as ".50", normalizing them to "0.50"
+	AllowNumericAmounts      bool   // Accept JSON numbers for the total and item prices, rounding them to cents
+	NumericHalfCent          string // Handling of numeric amounts exactly on a half cent: "reject", "up" or "even"
+	AllowMissingTime         bool   // Accept receipts without a purchase time; they earn no time-of-day points
+
+	RejectFuturePurchases   bool          // Reject receipts whose purchase date and time are later than the server clock
+	FuturePurchaseTolerance time.Duration // Clock skew allowed before a purchase counts as in the future
+	MaxPurchaseAge          time.Duration // Oldest purchase accepted, relative to the processing time; zero disables the check
+
+	DefaultTimezone *time.Location // Zone the purchase date and time of receipts that declare no timezone are written in
+
+	AuthSchemes []string // Authorization header schemes accepted for JWTs, matched case-insensitively
+
+	CORSAllowedOrigins []string // Browser origins allowed to call the API; "*" allows any, empty disables CORS
+
+	Environment string        // Deployment environment; "production" requires an explicit JWT secret
+	JWTSecret   string        // Key used to sign and verify JWTs; empty keeps the development key outside production
+	JWTTTL      time.Duration // Lifetime of issued tokens
+
+	RetryAfter      string // Retry-After value (seconds or HTTP-date) sent with 429 and 503 responses
+	MaintenanceMode bool   // Answer everything but /healthz and /metrics with 503 Service Unavailable
+
+	RateLimit        RateLimit            // Token bucket given to each client; a zero rate disables rate limiting
+	RateLimitTenants map[string]RateLimit // Token bucket shared by each tenant's clients, overriding RateLimit for that tenant
+
+	DebugTiming bool // Include processingMs in ProcessReceipt responses; leaks timing, so off in production
+
+	StoreBackend string // Receipt store implementation: "memory" or "bolt"
+	StorePath    string // Database file used by the bolt store backend
+	StoreShards  int    // Number of independently locked shards in the in-memory store; 1 uses a single lock
+
+	GzipMinSize int // Smallest response body, in bytes, that is gzip-compressed for clients accepting it
+
+	StoreMaxHashes int // Cap on duplicate-detection hash entries kept by the in-memory store, evicting the least recently used; 0 is unbounded
+
+	StoreSecondaryBackend string // Store that also receives every write during a migration; empty disables dual writes
+	StoreSecondaryPath    string // Database file used when the secondary backend is "bolt"
+	StoreReadFallback     bool   // Read from the secondary store when the primary has no receipt for an ID
+
+	CoalesceReads bool // Share one store read between concurrent points lookups of the same receipt
+	StoreRawBody  bool // Keep the exact submitted bytes of each receipt for auditing disputes
+
+	Credentials map[string]Credential // Accounts accepted by the login endpoint, keyed by username
+
+	ReadyDegradedLatency time.Duration // Store ping latency above which readiness reports "degraded"; zero disables
+
+	MaxJSONDepth int   // Deepest nesting of objects and arrays accepted in receipt bodies; zero disables
+	MaxBatchSize int   // Most receipts accepted in one batch request; zero disables the check
+	MaxLookupIDs int   // Most receipt IDs accepted in one batch points lookup; zero disables the check
+	MaxBodyBytes int64 // Largest request body accepted, in bytes; zero disables the limit
+
+	RouteMaxBodyBytes map[string]int64 // Per-route body limits keyed by unversioned route template, overriding MaxBodyBytes
+
+	Addr              string        // Address the server listens on, such as ":8080"
+	ReadTimeout       time.Duration // Longest time allowed to read a request, body included; zero means no limit
+	ReadHeaderTimeout time.Duration // Longest time allowed to read request headers; zero falls back to ReadTimeout
+	WriteTimeout      time.Duration // Longest time from the end of the request headers to the end of the response; zero means no limit
+	IdleTimeout       time.Duration // How long an idle keep-alive connection stays open; zero falls back to ReadTimeout
+
+	ShutdownTimeout time.Duration // How long in-flight requests may run after a shutdown signal
+	RequestTimeout  time.Duration // Deadline on each request's context; zero disables it
+
+	WebhookURL        string        // Endpoint notified of every newly processed receipt; empty disables webhooks
+	WebhookSecret     string        // Shared secret keying the HMAC-SHA256 signature of each webhook payload
+	WebhookMaxRetries int           // Times a failed webhook delivery is retried
+	WebhookBackoff    time.Duration // Wait before the first webhook retry, doubled for each later one
+	WebhookTimeout    time.Duration // How long a single webhook request may take
+
+	Tiers []Tier // Loyalty tiers, sorted by ascending MinPoints
+}
+
+// RateLimit is a token bucket: a client may send up to Burst requests at once and
+// regains RPS requests per second.
+type RateLimit struct {
+	RPS   float64 // Requests per second added to the bucket
+	Burst int     // Most requests the bucket holds
+}
+
+// Tier is a loyalty tier that receipts reach by earning at least MinPoints.
+type Tier struct {
+	Name      string // Tier name reported to clients, e.g. "Gold"
+	MinPoints int    // Fewest points that earn the tier
+}
+
+// Credential is a login account for the service.
+type Credential struct {
+	Password string // Password checked by the login endpoint
+	Role     string // Role embedded in the issued JWT
+	Tenant   string // Tenant embedded in the issued JWT; empty for accounts outside any tenant
+}
+
+// RuleConfig holds the parameters for the point calculation rules: the weights of
+// the base rules, which default to the original scoring, and the optional rules.
+// A zero value for a rule's points disables that rule.
+type RuleConfig struct {
+	Lenient bool // Skip (and log) a rule that panics instead of failing the whole request
+
+	RetailerCharPoints      int // Points awarded per alphanumeric character in the retailer name
+	RoundDollarBonus        int // Points awarded when the total has no cents
+	QuarterMultipleBonus    int // Points awarded when the total is a multiple of 0.25
+	ItemPairPoints          int // Points awarded for every two items
+	DescriptionPricePercent int // Percentage of the price, rounded up, awarded for items whose description length is a multiple of 3
+	OddDayBonus             int // Points awarded when the purchase day is odd
+	AfternoonBonus          int // Points awarded for purchases between 2:00pm and 4:00pm
+
+	TotalDigitSumMultiplier int // Points awarded per unit of the digit sum of the total in cents
+	FirstPurchaseOfDayBonus int // Points awarded to a user's earliest receipt on each purchase date
+	PrimeItemCountBonus     int // Points awarded when the number of items is a prime number
+	DistinctPricePoints     int // Points awarded for each distinct item price on the receipt
+	RetailerVowelPoints     int // Points awarded for each vowel in the retailer name
+	EvenCentsItemPoints     int // Points awarded for each item whose price has an even number of cents
+	DigitDescriptionPoints  int // Points awarded for each item whose description contains a digit
+	RoundDollarItemPoints   int // Points awarded for each item whose price is a round dollar amount
+
+	DescriptionLengthPoints int // Points awarded for every DescriptionLengthChars characters across all item descriptions
+	DescriptionLengthChars  int // Characters of item description needed per award of DescriptionLengthPoints
+
+	TotalModuloBonus int   // Points awarded when the total is an exact multiple of TotalModuloCents
+	TotalModuloCents int64 // Amount, in cents, the total must be a multiple of for the modulo bonus
+
+	UniformBasketBonus    int // Points awarded when every item has the same price
+	UniformBasketMinItems int // Fewest items a receipt needs for the uniform basket bonus
+
+	LuckyTotalBonus     int // Points awarded when the digits of the total contain a run of identical digits
+	LuckyTotalRunLength int // Length of the run of identical digits the lucky total bonus requires
+
+	RetailerChecksumBonus   int // Points awarded when the retailer name checksum equals RetailerChecksumTarget
+	RetailerChecksumModulus int // Modulus of the retailer name checksum
+	RetailerChecksumTarget  int // Checksum value that earns the retailer checksum bonus
+
+	CampaignBonus int       // Points awarded at the campaign start, decreasing linearly to zero at its end
+	CampaignStart time.Time // When the campaign begins
+	CampaignEnd   time.Time // When the campaign ends
+
+	PalindromeDateBonus  int    // Points awarded when the digits of the formatted purchase date read the same backwards
+	PalindromeDateFormat string // Go time layout the purchase date and time are formatted with for the palindrome check
+
+	HolidayBonus int      // Points awarded when the purchase date is a holiday
+	Holidays     []string // Holidays as recurring MM-DD dates or specific YYYY-MM-DD dates
+
+	MonthMultipliers map[time.Month]float64 // Multiplier applied to the final total by purchase month; missing months use 1.0
+}
+
+// Default returns the configuration used when no environment overrides are set.
+func Default() Config {
+	return Config{
+		AuthSchemes:  []string{"Bearer"},
+		Environment:  "development",
+		JWTTTL:       time.Hour,
+		RetryAfter:   "1",
+		StoreBackend: "memory",
+		StorePath:    "receipts.db",
+		StoreShards:  1,
+		GzipMinSize:  1024,
+
+		Rules: RuleConfig{
+			RetailerCharPoints:      1,
+			RoundDollarBonus:        50,
+			QuarterMultipleBonus:    25,
+			ItemPairPoints:          5,
+			DescriptionPricePercent: 20,
+			OddDayBonus:             6,
+			AfternoonBonus:          10,
+			UniformBasketMinItems:   2,
+			RetailerChecksumModulus: 10,
+			LuckyTotalRunLength:     4,
+			DescriptionLengthChars:  10,
+			PalindromeDateFormat:    "01-02-2006",
+		},
+
+		StoreSecondaryPath: "receipts-secondary.db",
+		StoreReadFallback:  true,
+
+		ReadyDegradedLatency: 100 * time.Millisecond,
+		MaxJSONDepth:         5,
+		MaxBodyBytes:         1 << 20,
+		MaxBatchSize:         1000,
+		MaxLookupIDs:         1000,
+		ShutdownTimeout:      10 * time.Second,
+
+		Addr:              ":8080",
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		RequestTimeout:    30 * time.Second,
+
+		WebhookMaxRetries: 3,
+		WebhookBackoff:    time.Second,
+		WebhookTimeout:    5 * time.Second,
+
+		NumericHalfCent:         utils.HalfCentReject,
+		RejectFuturePurchases:   true,
+		FuturePurchaseTolerance: 5 * time.Minute,
+		DefaultTimezone:         time.Local,
+
+		Tiers: []Tier{
+			{Name: "Bronze", MinPoints: 0},
+			{Name: "Silver", MinPoints: 50},
+			{Name: "Gold", MinPoints: 100},
+		},
+
+		// Development account; override with USERS outside local testing
+		Credentials: map[string]Credential{
+			"saurabh": {Password: "password", Role: "user"},
+		},
+	}
+}
+
+// Load builds the configuration from environment variables, falling back to
+// the defaults for any variable that is unset or malformed.
+func Load() Config {
+	cfg := Default()
+
+	cfg.Rules.Lenient = envBool("SCORING_LENIENT", cfg.Rules.Lenient)
+	cfg.Rules.RetailerCharPoints = envInt("RULE_RETAILER_CHAR_POINTS", cfg.Rules.RetailerCharPoints)
+	cfg.Rules.RoundDollarBonus = envInt("RULE_ROUND_DOLLAR_BONUS", cfg.Rules.RoundDollarBonus)
+	cfg.Rules.QuarterMultipleBonus = envInt("RULE_QUARTER_MULTIPLE_BONUS", cfg.Rules.QuarterMultipleBonus)
+	cfg.Rules.ItemPairPoints = envInt("RULE_ITEM_PAIR_POINTS", cfg.Rules.ItemPairPoints)
+	cfg.Rules.DescriptionPricePercent = envInt("RULE_DESCRIPTION_PRICE_PERCENT", cfg.Rules.DescriptionPricePercent)
+	cfg.Rules.OddDayBonus = envInt("RULE_ODD_DAY_BONUS", cfg.Rules.OddDayBonus)
+	cfg.Rules.AfternoonBonus = envInt("RULE_AFTERNOON_BONUS", cfg.Rules.AfternoonBonus)
+	cfg.Rules.TotalDigitSumMultiplier = envInt("RULE_TOTAL_DIGIT_SUM_MULTIPLIER", cfg.Rules.TotalDigitSumMultiplier)
+	cfg.Rules.FirstPurchaseOfDayBonus = envInt("RULE_FIRST_PURCHASE_OF_DAY_BONUS", cfg.Rules.FirstPurchaseOfDayBonus)
+	cfg.Rules.PrimeItemCountBonus = envInt("RULE_PRIME_ITEM_COUNT_BONUS", cfg.Rules.PrimeItemCountBonus)
+	cfg.Rules.DistinctPricePoints = envInt("RULE_DISTINCT_PRICE_POINTS", cfg.Rules.DistinctPricePoints)
+	cfg.Rules.EvenCentsItemPoints = envInt("RULE_EVEN_CENTS_ITEM_POINTS", cfg.Rules.EvenCentsItemPoints)
+	cfg.Rules.DigitDescriptionPoints = envInt("RULE_DIGIT_DESCRIPTION_POINTS", cfg.Rules.DigitDescriptionPoints)
+	cfg.Rules.RoundDollarItemPoints = envInt("RULE_ROUND_DOLLAR_ITEM_POINTS", cfg.Rules.RoundDollarItemPoints)
+	cfg.Rules.DescriptionLengthPoints = envInt("RULE_DESCRIPTION_LENGTH_POINTS", cfg.Rules.DescriptionLengthPoints)
+	cfg.Rules.DescriptionLengthChars = envInt("RULE_DESCRIPTION_LENGTH_CHARS", cfg.Rules.DescriptionLengthChars)
+	cfg.Rules.RetailerVowelPoints = envInt("RULE_RETAILER_VOWEL_POINTS", cfg.Rules.RetailerVowelPoints)
+	cfg.Rules.TotalModuloBonus = envInt("RULE_TOTAL_MODULO_BONUS", cfg.Rules.TotalModuloBonus)
+	cfg.Rules.TotalModuloCents = envCents("RULE_TOTAL_MODULO_AMOUNT", cfg.Rules.TotalModuloCents)
+	cfg.Rules.UniformBasketBonus = envInt("RULE_UNIFORM_BASKET_BONUS", cfg.Rules.UniformBasketBonus)
+	cfg.Rules.UniformBasketMinItems = envInt("RULE_UNIFORM_BASKET_MIN_ITEMS", cfg.Rules.UniformBasketMinItems)
+	cfg.Rules.LuckyTotalBonus = envInt("RULE_LUCKY_TOTAL_BONUS", cfg.Rules.LuckyTotalBonus)
+	cfg.Rules.LuckyTotalRunLength = envInt("RULE_LUCKY_TOTAL_RUN_LENGTH", cfg.Rules.LuckyTotalRunLength)
+	cfg.Rules.RetailerChecksumBonus = envInt("RULE_RETAILER_CHECKSUM_BONUS", cfg.Rules.RetailerChecksumBonus)
+	cfg.Rules.RetailerChecksumModulus = envInt("RULE_RETAILER_CHECKSUM_MODULUS", cfg.Rules.RetailerChecksumModulus)
+	cfg.Rules.RetailerChecksumTarget = envInt("RULE_RETAILER_CHECKSUM_TARGET", cfg.Rules.RetailerChecksumTarget)
+	cfg.Rules.CampaignBonus = envInt("RULE_CAMPAIGN_BONUS", cfg.Rules.CampaignBonus)
+	cfg.Rules.CampaignStart = envTime("RULE_CAMPAIGN_START", cfg.Rules.CampaignStart)
+	cfg.Rules.CampaignEnd = envTime("RULE_CAMPAIGN_END", cfg.Rules.CampaignEnd)
+	cfg.Rules.PalindromeDateBonus = envInt("RULE_PALINDROME_DATE_BONUS", cfg.Rules.PalindromeDateBonus)
+	cfg.Rules.PalindromeDateFormat = envString("RULE_PALINDROME_DATE_FORMAT", cfg.Rules.PalindromeDateFormat)
+	cfg.Rules.HolidayBonus = envInt("RULE_HOLIDAY_BONUS", cfg.Rules.HolidayBonus)
+	cfg.Rules.Holidays = envList("RULE_HOLIDAYS", cfg.Rules.Holidays)
+
+	// RULE_MONTH_MULTIPLIERS is a comma-separated list of month=multiplier pairs, e.g. "12=1.5"
+	for month, multiplier := range envMap("RULE_MONTH_MULTIPLIERS", nil) {
+		m, err := strconv.Atoi(month)
+		f, ferr := strconv.ParseFloat(multiplier, 64)
+		if err != nil || ferr != nil || m < 1 || m > 12 || f < 0 {
+			continue
+		}
+		if cfg.Rules.MonthMultipliers == nil {
+			cfg.Rules.MonthMultipliers = make(map[time.Month]float64)
+		}
+		cfg.Rules.MonthMultipliers[time.Month(m)] = f
+	}
+
+	// FIELD_ALIASES is a comma-separated list of alias=field pairs, e.g. "store=retailer,date=purchaseDate"
+	cfg.FieldAliases = envMap("FIELD_ALIASES", cfg.FieldAliases)
+	cfg.Envelope = envBool("ENVELOPE", cfg.Envelope)
+	cfg.PointsAsString = envBool("POINTS_AS_STRING", cfg.PointsAsString)
+	cfg.MaxItemPriceCents = envCents("MAX_ITEM_PRICE", cfg.MaxItemPriceCents)
+
+	// VALIDATION_CHECKS is a comma-separated list of check=severity pairs, e.g. "itemTotal=warning"
+	for check, severity := range envMap("VALIDATION_CHECKS", nil) {
+		if severity != "error" && severity != "warning" && severity != "off" {
+			continue
+		}
+		if cfg.ValidationChecks == nil {
+			cfg.ValidationChecks = make(map[string]string)
+		}
+		cfg.ValidationChecks[check] = severity
+	}
+	cfg.ReservedRetailers = envList("RESERVED_RETAILERS", cfg.ReservedRetailers)
+	cfg.ReservedRetailerAllowedUsers = envList("RESERVED_RETAILER_ALLOWED_USERS", cfg.ReservedRetailerAllowedUsers)
+	cfg.DefaultRetailer = envString("DEFAULT_RETAILER", cfg.DefaultRetailer)
+	cfg.MaxReceiptsPerRetailerPerDay = envInt("MAX_RECEIPTS_PER_RETAILER_PER_DAY", cfg.MaxReceiptsPerRetailerPerDay)
+	cfg.AllowPurchaseTimeSeconds = envBool("ALLOW_PURCHASE_TIME_SECONDS", cfg.AllowPurchaseTimeSeconds)
+	cfg.AllowMissingLeadingZero = envBool("ALLOW_MISSING_LEADING_ZERO", cfg.AllowMissingLeadingZero)
+	cfg.AllowNumericAmounts = envBool("ALLOW_NUMERIC_AMOUNTS", cfg.AllowNumericAmounts)
+	switch policy := envString("NUMERIC_HALF_CENT", cfg.NumericHalfCent); policy {
+	case utils.HalfCentReject, utils.HalfCentUp, utils.HalfCentEven:
+		cfg.NumericHalfCent = policy
+	}
+	cfg.AllowMissingTime = envBool("ALLOW_MISSING_TIME", cfg.AllowMissingTime)
+	cfg.RejectFuturePurchases = envBool("REJECT_FUTURE_PURCHASES", cfg.RejectFuturePurchases)
+	cfg.FuturePurchaseTolerance = envDuration("FUTURE_PURCHASE_TOLERANCE", cfg.FuturePurchaseTolerance)
+	cfg.MaxPurchaseAge = envDuration("MAX_PURCHASE_AGE", cfg.MaxPurchaseAge)
+	if name := envString("DEFAULT_TIMEZONE", ""); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			cfg.DefaultTimezone = loc
+		}
+	}
+	cfg.AuthSchemes = envList("AUTH_SCHEMES", cfg.AuthSchemes)
+	cfg.CORSAllowedOrigins = envList("CORS_ALLOWED_ORIGINS", cfg.CORSAllowedOrigins)
+	cfg.Environment = envString("APP_ENV", cfg.Environment)
+	cfg.JWTSecret = envString("JWT_SECRET", cfg.JWTSecret)
+	cfg.JWTTTL = envDuration("JWT_TTL", cfg.JWTTTL)
+
+	// RATE_LIMIT requests per RATE_LIMIT_WINDOW is shorthand for a bucket of that size
+	// refilled over the window; RATE_LIMIT_RPS and RATE_LIMIT_BURST set the bucket directly
+	window := envDuration("RATE_LIMIT_WINDOW", time.Minute)
+	if limit, ok := parseRateLimit(envString("RATE_LIMIT", ""), window); ok {
+		cfg.RateLimit = limit
+	}
+	cfg.RateLimit.RPS = envFloat("RATE_LIMIT_RPS", cfg.RateLimit.RPS)
+	cfg.RateLimit.Burst = envInt("RATE_LIMIT_BURST", cfg.RateLimit.Burst)
+	if cfg.RateLimit.RPS > 0 && cfg.RateLimit.Burst < 1 {
+		cfg.RateLimit.Burst = int(math.Max(1, math.Ceil(cfg.RateLimit.RPS)))
+	}
+
+	// RATE_LIMIT_TENANTS is a comma-separated list of tenant=rps:burst pairs, or tenant=limit
+	// for limit requests per RATE_LIMIT_WINDOW, e.g. "acme=10:20,globex=60"
+	for tenant, v := range envMap("RATE_LIMIT_TENANTS", nil) {
+		limit, ok := parseRateLimit(v, window)
+		if !ok {
+			continue
+		}
+		if cfg.RateLimitTenants == nil {
+			cfg.RateLimitTenants = make(map[string]RateLimit)
+		}
+		cfg.RateLimitTenants[tenant] = limit
+	}
+	cfg.DebugTiming = envBool("DEBUG_TIMING", cfg.DebugTiming)
+	cfg.MaintenanceMode = envBool("MAINTENANCE_MODE", cfg.MaintenanceMode)
+	cfg.StoreBackend = envString("STORE_BACKEND", cfg.StoreBackend)
+	cfg.StorePath = envString("STORE_PATH", cfg.StorePath)
+	cfg.StoreShards = envInt("STORE_SHARDS", cfg.StoreShards)
+	cfg.GzipMinSize = envInt("GZIP_MIN_SIZE", cfg.GzipMinSize)
+	cfg.StoreMaxHashes = envInt("STORE_MAX_HASHES", cfg.StoreMaxHashes)
+	cfg.StoreSecondaryBackend = envString("STORE_SECONDARY_BACKEND", cfg.StoreSecondaryBackend)
+	cfg.StoreSecondaryPath = envString("STORE_SECONDARY_PATH", cfg.StoreSecondaryPath)
+	cfg.StoreReadFallback = envBool("STORE_READ_FALLBACK", cfg.StoreReadFallback)
+	cfg.CoalesceReads = envBool("COALESCE_READS", cfg.CoalesceReads)
+	cfg.StoreRawBody = envBool("STORE_RAW_BODY", cfg.StoreRawBody)
+	cfg.ReadyDegradedLatency = envDuration("READY_DEGRADED_LATENCY", cfg.ReadyDegradedLatency)
+	cfg.MaxJSONDepth = envInt("MAX_JSON_DEPTH", cfg.MaxJSONDepth)
+	cfg.MaxBodyBytes = int64(envInt("MAX_BODY_BYTES", int(cfg.MaxBodyBytes)))
+
+	// ROUTE_MAX_BODY_BYTES is a comma-separated list of route=bytes pairs, e.g. "/receipts/process/batch=10485760"
+	for route, v := range envMap("ROUTE_MAX_BODY_BYTES", nil) {
+		limit, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || limit < 0 {
+			continue
+		}
+		if cfg.RouteMaxBodyBytes == nil {
+			cfg.RouteMaxBodyBytes = make(map[string]int64)
+		}
+		cfg.RouteMaxBodyBytes[route] = limit
+	}
+	cfg.MaxBatchSize = envInt("MAX_BATCH_SIZE", cfg.MaxBatchSize)
+	cfg.MaxLookupIDs = envInt("MAX_LOOKUP_IDS", cfg.MaxLookupIDs)
+	cfg.ShutdownTimeout = envDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+
+	// ADDR is a full listen address such as "127.0.0.1:9000"; PORT only sets the port
+	if port := envString("PORT", ""); port != "" {
+		cfg.Addr = ":" + port
+	}
+	if addr := envString("ADDR", ""); addr != "" {
+		cfg.Addr = addr
+	}
+	cfg.ReadTimeout = envDuration("SERVER_READ_TIMEOUT", cfg.ReadTimeout)
+	cfg.ReadHeaderTimeout = envDuration("SERVER_READ_HEADER_TIMEOUT", cfg.ReadHeaderTimeout)
+	cfg.WriteTimeout = envDuration("SERVER_WRITE_TIMEOUT", cfg.WriteTimeout)
+	cfg.IdleTimeout = envDuration("SERVER_IDLE_TIMEOUT", cfg.IdleTimeout)
+	cfg.RequestTimeout = envDuration("REQUEST_TIMEOUT", cfg.RequestTimeout)
+	cfg.WebhookURL = envString("WEBHOOK_URL", cfg.WebhookURL)
+	cfg.WebhookSecret = envString("WEBHOOK_SECRET", cfg.WebhookSecret)
+	cfg.WebhookMaxRetries = envInt("WEBHOOK_MAX_RETRIES", cfg.WebhookMaxRetries)
+	cfg.WebhookBackoff = envDuration("WEBHOOK_BACKOFF", cfg.WebhookBackoff)
+	cfg.WebhookTimeout = envDuration("WEBHOOK_TIMEOUT", cfg.WebhookTimeout)
+
+	// USERS is a comma-separated list of username:password[:role[:tenant]] entries; the role defaults to "user"
+	if users, ok := os.LookupEnv("USERS"); ok {
+		cfg.Credentials = make(map[string]Credential)
+		for _, entry := range strings.Split(users, ",") {
+			parts := strings.SplitN(strings.TrimSpace(entry), ":", 4)
+			if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+				continue
+			}
+			cred := Credential{Password: parts[1], Role: "user"}
+			if len(parts) >= 3 && parts[2] != "" {
+				cred.Role = parts[2]
+			}
+			if len(parts) == 4 {
+				cred.Tenant = parts[3]
+			}
+			cfg.Credentials[parts[0]] = cred
+		}
+	}
+
+	// POINT_TIERS is a comma-separated list of name=minPoints pairs, e.g. "Bronze=0,Silver=50,Gold=100"
+	if _, ok := os.LookupEnv("POINT_TIERS"); ok {
+		cfg.Tiers = nil
+		for name, minPoints := range envMap("POINT_TIERS", nil) {
+			n, err := strconv.Atoi(minPoints)
+			if err != nil {
+				continue
+			}
+			cfg.Tiers = append(cfg.Tiers, Tier{Name: name, MinPoints: n})
+		}
+		sort.Slice(cfg.Tiers, func(i, j int) bool { return cfg.Tiers[i].MinPoints < cfg.Tiers[j].MinPoints })
+	}
+
+	// RETRY_AFTER must be a non-negative number of seconds or an HTTP-date
+	if v := envString("RETRY_AFTER", cfg.RetryAfter); isRetryAfter(v) {
+		cfg.RetryAfter = v
+	}
+
+	return cfg
+}
+
+// Validate reports settings that make the configuration unsafe to run with.
+func (c Config) Validate() error {
+	if c.Environment == "production" && c.JWTSecret == "" {
+		return errors.New("JWT_SECRET must be set when APP_ENV is production")
+	}
+	if c.Environment == "production" && c.WebhookURL != "" && c.WebhookSecret == "" {
+		return errors.New("WEBHOOK_SECRET must be set when WEBHOOK_URL is set and APP_ENV is production")
+	}
+	// The development account's password is published in the README
+	for username, builtIn := range Default().Credentials {
+		if cred, exists := c.Credentials[username]; c.Environment == "production" && exists && cred.Password == builtIn.Password {
+			return fmt.Errorf("the built-in %q account must be replaced with USERS when APP_ENV is production", username)
+		}
+	}
+	if c.JWTTTL <= 0 {
+		return errors.New("JWT_TTL must be positive")
+	}
+	return nil
+}
+
+// parseRateLimit parses a token bucket written as "rps:burst", or as a plain request
+// count allowed per window.
+func parseRateLimit(v string, window time.Duration) (RateLimit, bool) {
+	if rps, burst, found := strings.Cut(v, ":"); found {
+		r, err := strconv.ParseFloat(rps, 64)
+		b, berr := strconv.Atoi(burst)
+		if err != nil || berr != nil || r < 0 || b < 1 {
+			return RateLimit{}, false
+		}
+		return RateLimit{RPS: r, Burst: b}, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || window <= 0 {
+		return RateLimit{}, false
+	}
+	return RateLimit{RPS: float64(n) / window.Seconds(), Burst: n}, true
+}
+
+// isRetryAfter reports whether v is a valid Retry-After value: delay seconds or an HTTP-date.
+func isRetryAfter(v string) bool {
+	if n, err := strconv.Atoi(v); err == nil {
+		return n >= 0
+	}
+	_, err := http.ParseTime(v)
+	return err == nil
+}
+
+// Helper functions for reading environment variables
+
+// envString reads a string environment variable, returning def if it is unset.
+func envString(key, def string) string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	return strings.TrimSpace(v)
+}
+
+// envInt reads an integer environment variable, returning def if it is unset or invalid.
+func envInt(key string, def int) int {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// envFloat reads a non-negative decimal environment variable, returning def if it is unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || f < 0 {
+		return def
+	}
+	return f
+}
+
+// envCents reads an amount formatted as "0.00" and returns it in cents,
+// returning def if it is unset or invalid.
+func envCents(key string, def int64) int64 {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	cents, err := utils.ParseCents(strings.TrimSpace(v))
+	if err != nil {
+		return def
+	}
+	return cents
+}
+
+// envDuration reads a Go duration such as "250ms", returning def if it is unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil || d < 0 {
+		return def
+	}
+	return d
+}
+
+// envTime reads an RFC 3339 timestamp such as "2024-11-01T00:00:00Z", returning def if it is unset or invalid.
+func envTime(key string, def time.Time) time.Time {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(v))
+	if err != nil {
+		return def
+	}
+	return t
+}
+
+// envBool reads a boolean environment variable, returning def if it is unset or invalid.
+func envBool(key string, def bool) bool {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+// envList reads a comma-separated list, returning def if it is unset. Empty entries are dropped.
+func envList(key string, def []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// envMap reads a comma-separated list of key=value pairs, returning def if it is unset.
+// Malformed pairs are ignored.
+func envMap(key string, def map[string]string) map[string]string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		k, val, found := strings.Cut(pair, "=")
+		k, val = strings.TrimSpace(k), strings.TrimSpace(val)
+		if !found || k == "" || val == "" {
+			continue
+		}
+		m[k] = val
+	}
+	return m
+}
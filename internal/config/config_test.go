@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestValidateRejectsBuiltInCredentialsInProduction(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		environment string
+		credentials map[string]Credential
+		ok          bool
+	}{
+		{"development with the built-in account", "development", Default().Credentials, true},
+		{"production with the built-in account", "production", Default().Credentials, false},
+		{"production with the built-in account among others", "production", map[string]Credential{
+			"saurabh": {Password: "password"},
+			"alice":   {Password: "s3cret"},
+		}, false},
+		{"production with the built-in username and a new password", "production", map[string]Credential{"saurabh": {Password: "s3cret"}}, true},
+		{"production with configured accounts", "production", map[string]Credential{"alice": {Password: "s3cret"}}, true},
+	} {
+		c := Default()
+		c.Environment = tc.environment
+		c.JWTSecret = "secret"
+		c.Credentials = tc.credentials
+		if err := c.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tc.name, err, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// admin.go
+// This file contains the administrative handlers, which require a JWT with the admin role.
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// pointsHistogramBounds are the inclusive upper bounds of the points-per-receipt
+// histogram buckets. Receipts above the last bound fall into an overflow bucket.
+var pointsHistogramBounds = []int{10, 25, 50, 100, 250, 500, 1000}
+
+// histogramBucket counts the receipts whose points fall at or below a bound.
+type histogramBucket struct {
+	LE    string `json:"le"`    // Upper bound of the bucket, or "+Inf" for the overflow bucket
+	Count int    `json:"count"` // Number of receipts in this bucket (not cumulative)
+}
+
+// ruleStats aggregates the contribution of a single rule across all receipts.
+type ruleStats struct {
+	Rule     string `json:"rule"`     // Identifier of the rule
+	Points   int    `json:"points"`   // Total points the rule has awarded
+	Receipts int    `json:"receipts"` // Number of receipts the rule awarded points to
+}
+
+// scoringStatsResponse is the body returned by GetScoringStats.
+type scoringStatsResponse struct {
+	Receipts    int               `json:"receipts"`    // Number of stored receipts
+	TotalPoints int               `json:"totalPoints"` // Sum of points across all stored receipts
+	Histogram   []histogramBucket `json:"histogram"`   // Distribution of points per receipt
+	Rules       []ruleStats       `json:"rules"`       // Points contributed by each rule
+}
+
+// retailerStats aggregates the receipts of a single retailer.
+type retailerStats struct {
+	Retailer      string  `json:"retailer"`      // Retailer name as submitted
+	Receipts      int     `json:"receipts"`      // Number of receipts from the retailer
+	Points        int     `json:"points"`        // Sum of points across those receipts
+	AveragePoints float64 `json:"averagePoints"` // Mean points per receipt, rounded to two decimals
+}
+
+// retailerAnalyticsResponse is the body returned by GetRetailerAnalytics.
+type retailerAnalyticsResponse struct {
+	Retailers []retailerStats `json:"retailers"` // One entry per retailer, sorted by name
+}
+
+// RequireRole wraps next so that it only runs for requests carrying a valid JWT with
+// the given role. Requests without a valid token are answered with 401 and those
+// whose token has another role with 403.
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorizeRole(w, r, role) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// authorizeAdmin verifies the request carries a valid JWT with the admin role,
+// writing a 401 or 403 response and returning false when it does not.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	return authorizeRole(w, r, utils.RoleAdmin)
+}
+
+// authorizeRole verifies the request carries a valid JWT with the given role,
+// writing a 401 or 403 response and returning false when it does not.
+func authorizeRole(w http.ResponseWriter, r *http.Request, role string) bool {
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	if claims.Role != role {
+		writeError(w, r, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	return true
+}
+
+// GetScoringStats handles the GET request for scoring analytics. It aggregates the
+// stored per-rule breakdowns into a points histogram and per-rule totals.
+func (h *Handler) GetScoringStats(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	resp := scoringStatsResponse{Histogram: make([]histogramBucket, len(pointsHistogramBounds)+1)}
+	for i, bound := range pointsHistogramBounds {
+		resp.Histogram[i].LE = strconv.Itoa(bound)
+	}
+	resp.Histogram[len(pointsHistogramBounds)].LE = "+Inf"
+
+	// Aggregate the persisted breakdowns
+	byRule := make(map[string]*ruleStats)
+	if err := h.store.RangeContext(r.Context(), func(stored *models.ProcessedReceipt) bool {
+		resp.Receipts++
+		resp.TotalPoints += stored.Points
+		resp.Histogram[histogramBucketIndex(stored.Points)].Count++
+
+		for _, result := range stored.Breakdown {
+			stats, ok := byRule[result.Rule]
+			if !ok {
+				stats = &ruleStats{Rule: result.Rule}
+				byRule[result.Rule] = stats
+			}
+			stats.Points += result.Points
+			stats.Receipts++
+		}
+		return true
+	}); err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+
+	// Report rules in a deterministic order
+	resp.Rules = make([]ruleStats, 0, len(byRule))
+	for _, stats := range byRule {
+		resp.Rules = append(resp.Rules, *stats)
+	}
+	sort.Slice(resp.Rules, func(i, j int) bool { return resp.Rules[i].Rule < resp.Rules[j].Rule })
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// GetRetailerAnalytics handles the GET request for points analytics per retailer.
+// It groups the stored receipts by retailer name, reporting the receipt count, the
+// sum of points and the average points of each, and honors the same optional from
+// and to processing time filters as the listing endpoint.
+func (h *Handler) GetRetailerAnalytics(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	from, to, ok := parseTimeWindow(w, r)
+	if !ok {
+		return
+	}
+
+	// Aggregate the receipts processed within the window by retailer
+	byRetailer := make(map[string]*retailerStats)
+	if err := h.store.RangeContext(r.Context(), func(stored *models.ProcessedReceipt) bool {
+		if !processedWithin(stored, from, to) {
+			return true
+		}
+		stats, ok := byRetailer[stored.Receipt.Retailer]
+		if !ok {
+			stats = &retailerStats{Retailer: stored.Receipt.Retailer}
+			byRetailer[stats.Retailer] = stats
+		}
+		stats.Receipts++
+		stats.Points += stored.Points
+		return true
+	}); err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+
+	// Report retailers in a deterministic order
+	resp := retailerAnalyticsResponse{Retailers: make([]retailerStats, 0, len(byRetailer))}
+	for _, stats := range byRetailer {
+		stats.AveragePoints = math.Round(float64(stats.Points)/float64(stats.Receipts)*100) / 100
+		resp.Retailers = append(resp.Retailers, *stats)
+	}
+	sort.Slice(resp.Retailers, func(i, j int) bool { return resp.Retailers[i].Retailer < resp.Retailers[j].Retailer })
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// GetAdminReceipt handles the GET request for the full stored state of a receipt,
+// including its owner, original payload and rule breakdown, for debugging. Every
+// field of the stored ProcessedReceipt is returned. The service records no rules
+// version, change history, client IP or external ID for a receipt, so those are
+// not part of the response; recalculation replaces the points and breakdown in
+// place, and modifiedAt tells when that last happened.
+func (h *Handler) GetAdminReceipt(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	// Retrieve the receipt from the store, rejecting malformed IDs
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	receipt, exists, err := h.store.GetContext(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+
+	if !exists {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, receipt)
+}
+
+const (
+	maxTokensPerRequest = 1000           // Largest number of tokens GenerateTokens issues at once
+	maxTokenTTL         = 24 * time.Hour // Longest lifetime GenerateTokens grants
+)
+
+// tokensRequest is the body accepted by GenerateTokens.
+type tokensRequest struct {
+	Usernames []string `json:"usernames"` // Subjects to issue tokens for
+	TTL       string   `json:"ttl"`       // Token lifetime as a Go duration such as "30m"; defaults to 1h
+}
+
+// tokensResponse maps each requested username to its signed token.
+type tokensResponse struct {
+	Tokens map[string]string `json:"tokens"`
+}
+
+// GenerateTokens handles the POST request to bulk-issue user tokens for testing.
+// Each token is signed with GenerateJWTWithTTL and carries the user role.
+func GenerateTokens(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	// Parse the JSON body into the token request
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var req tokensRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	// Validate the requested usernames and lifetime
+	if len(req.Usernames) == 0 {
+		writeError(w, r, http.StatusBadRequest, "at least one username is required")
+		return
+	}
+	if len(req.Usernames) > maxTokensPerRequest {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("at most %d usernames may be requested", maxTokensPerRequest))
+		return
+	}
+	ttl := time.Hour
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d <= 0 || d > maxTokenTTL {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("ttl must be a positive duration no longer than %s", maxTokenTTL))
+			return
+		}
+		ttl = d
+	}
+
+	resp := tokensResponse{Tokens: make(map[string]string, len(req.Usernames))}
+	for _, username := range req.Usernames {
+		if username == "" {
+			writeError(w, r, http.StatusBadRequest, "usernames must not be empty")
+			return
+		}
+		token, err := utils.GenerateJWTWithTTL(username, utils.RoleUser, ttl)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+		resp.Tokens[username] = token
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// histogramBucketIndex returns the index of the histogram bucket that holds points.
+func histogramBucketIndex(points int) int {
+	for i, bound := range pointsHistogramBounds {
+		if points <= bound {
+			return i
+		}
+	}
+	return len(pointsHistogramBounds)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// authorizeAdminRequest adds a bearer token for an admin to r.
+func authorizeAdminRequest(t testing.TB, r *http.Request) {
+	t.Helper()
+	token, err := utils.GenerateJWTWithRole("admin", utils.RoleAdmin)
+	if err != nil {
+		t.Fatalf("GenerateJWTWithRole: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+}
+
+// getAdminReceipt fetches the full stored state of receipt id as an admin.
+func getAdminReceipt(t testing.TB, h *Handler, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v1/admin/receipts/"+id, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	authorizeAdminRequest(t, r)
+	w := httptest.NewRecorder()
+	h.GetAdminReceipt(w, r)
+	return w
+}
+
+func TestAdminReceiptIncludesEveryPersistedField(t *testing.T) {
+	h := newTestHandler(t, func(c *config.Config) { c.StoreRawBody = true })
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	// Warnings are only stored when a quality check finds a problem
+	stored, err := h.store.Update(id, func(r *models.ProcessedReceipt) { r.Warnings = []string{"example warning"} })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := getAdminReceipt(t, h, id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	typ := reflect.TypeOf(models.ProcessedReceipt{})
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if _, ok := fields[name]; !ok {
+			t.Errorf("response has no %q field", name)
+		}
+	}
+
+	// Every field carries the stored value
+	var got models.ProcessedReceipt
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(stored)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("admin receipt differs from the stored one:\n got %s\nwant %s", gotJSON, wantJSON)
+	}
+}
+
+func TestScoringStats(t *testing.T) {
+	h := newTestHandler(t, nil)
+	for _, r := range []*models.ProcessedReceipt{
+		{ID: "a", Points: 5, Breakdown: []models.RuleResult{{Rule: "alphanumericChars", Points: 5}}},
+		{ID: "b", Points: 30, Breakdown: []models.RuleResult{{Rule: "alphanumericChars", Points: 10}, {Rule: "roundDollar", Points: 50}, {Rule: "penalty", Points: -30}}},
+		{ID: "c", Points: 50, Breakdown: []models.RuleResult{{Rule: "roundDollar", Points: 50}}},
+		{ID: "d", Points: 2000, Breakdown: []models.RuleResult{{Rule: "roundDollar", Points: 2000}}},
+	} {
+		if err := h.store.Save(r.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/admin/stats/scoring", nil)
+	authorizeAdminRequest(t, r)
+	w := httptest.NewRecorder()
+	h.GetScoringStats(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+	}
+	var got scoringStatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Receipts != 4 || got.TotalPoints != 2085 {
+		t.Errorf("receipts = %d, totalPoints = %d; want 4 and 2085", got.Receipts, got.TotalPoints)
+	}
+	wantHistogram := []histogramBucket{{"10", 1}, {"25", 0}, {"50", 2}, {"100", 0}, {"250", 0}, {"500", 0}, {"1000", 0}, {"+Inf", 1}}
+	if !reflect.DeepEqual(got.Histogram, wantHistogram) {
+		t.Errorf("histogram = %v, want %v", got.Histogram, wantHistogram)
+	}
+	wantRules := []ruleStats{
+		{Rule: "alphanumericChars", Points: 15, Receipts: 2},
+		{Rule: "penalty", Points: -30, Receipts: 1},
+		{Rule: "roundDollar", Points: 2100, Receipts: 3},
+	}
+	if !reflect.DeepEqual(got.Rules, wantRules) {
+		t.Errorf("rules = %v, want %v", got.Rules, wantRules)
+	}
+
+	// Only admins may read the statistics
+	r = httptest.NewRequest(http.MethodGet, "/v1/admin/stats/scoring", nil)
+	authorize(t, r, "alice")
+	w = httptest.NewRecorder()
+	h.GetScoringStats(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("user token: status %d, want 403", w.Code)
+	}
+}
+
+func TestGenerateTokens(t *testing.T) {
+	newTestHandler(t, nil)
+	post := func(contentType, body string, admin bool) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/v1/admin/tokens", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		if admin {
+			authorizeAdminRequest(t, r)
+		} else {
+			authorize(t, r, "alice")
+		}
+		w := httptest.NewRecorder()
+		GenerateTokens(w, r)
+		return w
+	}
+
+	w := post("application/json", `{"usernames": ["alice", "bob"], "ttl": "30m"}`, true)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d (%s), want 200", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	var resp tokensResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Tokens) != 2 {
+		t.Errorf("tokens = %v, want one each for alice and bob", resp.Tokens)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		check := httptest.NewRequest(http.MethodGet, "/", nil)
+		check.Header.Set("Authorization", "Bearer "+resp.Tokens[user])
+		claims, err := utils.ParseJWT(check)
+		if err != nil || claims.Subject != user || claims.Role != utils.RoleUser {
+			t.Errorf("%s: token claims = %+v, %v; want %s as a user", user, claims, err, user)
+			continue
+		}
+		if ttl := time.Until(claims.ExpiresAt.Time); ttl <= 29*time.Minute || ttl > 30*time.Minute {
+			t.Errorf("%s: token expires in %s, want 30m", user, ttl)
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		admin       bool
+		want        int
+	}{
+		{"user token", "application/json", `{"usernames": ["alice"]}`, false, http.StatusForbidden},
+		{"form body", "application/x-www-form-urlencoded", "usernames=alice", true, http.StatusUnsupportedMediaType},
+		{"nested too deeply", "application/json", `{"usernames": [[[[["alice"]]]]]}`, true, http.StatusBadRequest},
+		{"no usernames", "application/json", `{"usernames": []}`, true, http.StatusBadRequest},
+		{"ttl too long", "application/json", `{"usernames": ["alice"], "ttl": "48h"}`, true, http.StatusBadRequest},
+	} {
+		if w := post(tc.contentType, tc.body, tc.admin); w.Code != tc.want {
+			t.Errorf("%s: status %d (%s), want %d", tc.name, w.Code, strings.TrimSpace(w.Body.String()), tc.want)
+		}
+	}
+}
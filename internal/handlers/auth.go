@@ -0,0 +1,79 @@
+// auth.go
+// This file contains the authentication handlers, which issue JWTs to API clients.
+
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// loginRequest is the body accepted by Login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// tokenResponse is returned when a token is issued.
+type tokenResponse struct {
+	Token     string    `json:"token"`     // Signed JWT to send as "Authorization: Bearer <token>"
+	ExpiresAt time.Time `json:"expiresAt"` // When the token stops being accepted
+}
+
+// Login handles the POST request to authenticate with a username and password.
+// It verifies the credentials against the configured accounts and returns a signed
+// JWT carrying the account's role and tenant.
+func Login(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON body into the login request, with the same checks as every other JSON endpoint
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var req loginRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	// Verify the credentials, comparing passwords in constant time
+	cred, exists := cfg.Credentials[req.Username]
+	if !exists || req.Username == "" || subtle.ConstantTimeCompare([]byte(req.Password), []byte(cred.Password)) != 1 {
+		writeError(w, r, http.StatusUnauthorized, "Invalid username or password")
+		return
+	}
+
+	// Issue a token for the authenticated user
+	token, expiresAt, err := utils.IssueTenantJWT(req.Username, cred.Role, cred.Tenant, utils.TokenTTL())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, tokenResponse{Token: token, ExpiresAt: expiresAt})
+}
+
+// Refresh handles the POST request to renew a token. The token in the Authorization
+// header must still be valid; a fresh token with a new expiry is issued for the same
+// subject, role and tenant, so clients can stay authenticated without keeping the
+// password. Expired or malformed tokens are rejected with 401.
+func Refresh(w http.ResponseWriter, r *http.Request) {
+	// Verify the current token with the same checks as every other endpoint
+	claims, err := utils.ParseJWT(r)
+	if err != nil || claims.Subject == "" {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Issue a replacement token carrying the same identity
+	token, expiresAt, err := utils.IssueTenantJWT(claims.Subject, claims.Role, claims.Tenant, utils.TokenTTL())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, tokenResponse{Token: token, ExpiresAt: expiresAt})
+}
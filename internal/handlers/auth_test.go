@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+func TestLogin(t *testing.T) {
+	newTestHandler(t, func(c *config.Config) {
+		c.Credentials = map[string]config.Credential{"alice": {Password: "s3cret", Role: "admin"}}
+	})
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"valid credentials", "application/json", `{"username": "alice", "password": "s3cret"}`, http.StatusOK},
+		{"wrong password", "application/json", `{"username": "alice", "password": "guess"}`, http.StatusUnauthorized},
+		{"unknown user", "application/json", `{"username": "bob", "password": "s3cret"}`, http.StatusUnauthorized},
+		{"empty username", "application/json", `{"username": "", "password": ""}`, http.StatusUnauthorized},
+		{"malformed body", "application/json", `{"username": "alice",`, http.StatusBadRequest},
+		{"form body", "application/x-www-form-urlencoded", "username=alice&password=s3cret", http.StatusUnsupportedMediaType},
+		{"nested too deeply", "application/json", `{"username": [[[[["alice"]]]]], "password": "s3cret"}`, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/login", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			Login(w, r)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want != http.StatusOK {
+				return
+			}
+
+			// The issued token authenticates the account with its role
+			var resp tokenResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			check := httptest.NewRequest(http.MethodGet, "/", nil)
+			check.Header.Set("Authorization", "Bearer "+resp.Token)
+			claims, err := utils.ParseJWT(check)
+			if err != nil || claims.Subject != "alice" || claims.Role != "admin" {
+				t.Errorf("token claims = %+v, %v; want alice as admin", claims, err)
+			}
+		})
+	}
+}
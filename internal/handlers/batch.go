@@ -0,0 +1,104 @@
+// batch.go
+// This file contains the batch processing handler, which processes many receipts
+// in a single request.
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// ndjsonContentType is the media type of newline-delimited JSON streams
+const ndjsonContentType = "application/x-ndjson"
+
+// ProcessReceiptBatch handles the POST request to process an array of receipts.
+// Each receipt is validated, scored and stored independently, so one invalid
+// receipt does not fail the batch. Results pair each input index with either the
+// generated ID or an error message. When the client sends
+// "Accept: application/x-ndjson", results are streamed one per line as soon as
+// each receipt is processed instead of being returned as a single array. Batches
+// larger than the configured maximum are rejected with 400 before any is processed.
+func (h *Handler) ProcessReceiptBatch(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header for secure access
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the JSON array, deferring decoding of each receipt so that a malformed
+	// receipt is reported against its own index
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var rawReceipts []json.RawMessage
+	if err := json.Unmarshal(body, &rawReceipts); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if cfg.MaxBatchSize > 0 && len(rawReceipts) > cfg.MaxBatchSize {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Batch exceeds the maximum of %d receipts", cfg.MaxBatchSize))
+		return
+	}
+
+	ctx := r.Context()
+	if strings.Contains(r.Header.Get("Accept"), ndjsonContentType) {
+		h.streamBatch(ctx, w, rawReceipts, claims.Subject)
+		return
+	}
+
+	// Give up as soon as the request times out or the client disconnects. Receipts
+	// stored so far stay stored, and resubmitting them returns their existing IDs.
+	results := make([]models.BatchResult, 0, len(rawReceipts))
+	for idx, raw := range rawReceipts {
+		if err := ctx.Err(); err != nil {
+			writeContextError(w, r, err)
+			return
+		}
+		results = append(results, h.processBatchItem(ctx, idx, raw, claims.Subject))
+	}
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+// streamBatch processes the receipts in order, writing and flushing each result as
+// a line of NDJSON as soon as it is available. The stream ends early, after the last
+// complete line, once ctx is done.
+func (h *Handler) streamBatch(ctx context.Context, w http.ResponseWriter, rawReceipts []json.RawMessage, owner string) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	for idx, raw := range rawReceipts {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := enc.Encode(h.processBatchItem(ctx, idx, raw, owner)); err != nil {
+			return // The client has gone away
+		}
+		rc.Flush()
+	}
+}
+
+// processBatchItem decodes and processes a single receipt from a batch.
+func (h *Handler) processBatchItem(ctx context.Context, idx int, raw json.RawMessage, owner string) models.BatchResult {
+	var receipt models.Receipt
+	if err := decodeReceipt(bytes.NewReader(raw), &receipt); err != nil {
+		return models.BatchResult{Index: idx, Error: decodeErrorMessage(err)}
+	}
+
+	processed, _, err := h.processReceipt(ctx, &receipt, raw, owner)
+	if err != nil {
+		return models.BatchResult{Index: idx, Error: err.Error()}
+	}
+	return models.BatchResult{Index: idx, ID: processed.ID}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+)
+
+// gatedStore lets the first save through and holds every later one until gate is closed.
+type gatedStore struct {
+	store.ReceiptStore
+	saves int
+	gate  chan struct{}
+}
+
+func (s *gatedStore) SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error {
+	s.saves++
+	if s.saves > 1 {
+		<-s.gate
+	}
+	return s.ReceiptStore.SaveContext(ctx, id, receipt)
+}
+
+func TestBatchStreamIsReadIncrementally(t *testing.T) {
+	newTestHandler(t, nil)
+	s := &gatedStore{ReceiptStore: store.NewInMemoryStore(), gate: make(chan struct{})}
+	srv := httptest.NewServer(http.HandlerFunc(NewHandler(s).ProcessReceiptBatch))
+	defer srv.Close()
+
+	batch := "[" + strings.Join([]string{
+		withReceipt(t, "purchaseTime", "13:01"),
+		withReceipt(t, "purchaseTime", "13:02"),
+		withReceipt(t, "purchaseTime", "13:03"),
+	}, ",") + "]"
+	r, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(batch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", ndjsonContentType)
+	authorize(t, r, "alice")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != ndjsonContentType {
+		t.Fatalf("Content-Type = %q, want %s", got, ndjsonContentType)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	next := func(idx int) {
+		t.Helper()
+		select {
+		case line, ok := <-lines:
+			var result models.BatchResult
+			if !ok || json.Unmarshal([]byte(line), &result) != nil || result.Index != idx || result.ID == "" {
+				t.Fatalf("line %d = %q, want a result for index %d", idx, line, idx)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no line for index %d", idx)
+		}
+	}
+
+	// The first result arrives while the second receipt is still being saved
+	next(0)
+	close(s.gate)
+	next(1)
+	next(2)
+	if line, ok := <-lines; ok {
+		t.Errorf("unexpected line after the batch: %q", line)
+	}
+	if s.saves != 3 {
+		t.Errorf("%d saves, want 3", s.saves)
+	}
+}
+
+func TestMaxBatchSize(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		count int
+		want  int
+	}{
+		{"at the limit", 3, http.StatusOK},
+		{"above the limit", 4, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.MaxBatchSize = 3 })
+			receipts := make([]string, tc.count)
+			for i := range receipts {
+				receipts[i] = withReceipt(t, "purchaseTime", fmt.Sprintf("13:0%d", i))
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/v1/receipts/batch", strings.NewReader("["+strings.Join(receipts, ",")+"]"))
+			r.Header.Set("Content-Type", "application/json")
+			authorize(t, r, "alice")
+			w := httptest.NewRecorder()
+			h.ProcessReceiptBatch(w, r)
+			if w.Code != tc.want {
+				t.Fatalf("status %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tc.want)
+			}
+
+			stored := 0
+			h.store.Range(func(*models.ProcessedReceipt) bool { stored++; return true })
+			if tc.want != http.StatusOK {
+				if !strings.Contains(w.Body.String(), "maximum of 3 receipts") || stored != 0 {
+					t.Errorf("body %q with %d receipts stored, want the limit named and none stored", w.Body.String(), stored)
+				}
+				return
+			}
+			var results []models.BatchResult
+			if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != tc.count || stored != tc.count {
+				t.Errorf("%d results and %d receipts stored, want %d", len(results), stored, tc.count)
+			}
+		})
+	}
+}
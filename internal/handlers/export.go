@@ -0,0 +1,81 @@
+// export.go
+// This file contains the CSV export handler used for finance reporting.
+
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// exportFlushRows is how many CSV rows are written between flushes to the client
+const exportFlushRows = 100
+
+// exportHeader names the columns of the CSV export
+var exportHeader = []string{"id", "retailer", "purchaseDate", "purchaseTime", "total", "points"}
+
+// ExportReceipts handles the GET request to export stored receipts as CSV. The
+// matching receipts are collected first and the rows written afterwards, so a slow
+// client never holds the store's locks while it downloads; as stored receipts are
+// immutable snapshots, the collection holds only pointers to them. Like the listing
+// endpoint, it honors the optional from and to processing time filters.
+func (h *Handler) ExportReceipts(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header
+	if !utils.ValidateJWT(r) {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the optional processing time window before any of the body is written
+	from, to, ok := parseTimeWindow(w, r)
+	if !ok {
+		return
+	}
+
+	// Snapshot the matching receipts, releasing the store before any row is written
+	var receipts []*models.ProcessedReceipt
+	if err := h.store.RangeContext(r.Context(), func(stored *models.ProcessedReceipt) bool {
+		if processedWithin(stored, from, to) {
+			receipts = append(receipts, stored)
+		}
+		return true
+	}); err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="receipts.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	// Stop writing as soon as a write fails or the request's context ends, e.g. when
+	// the client disconnects; the status has been sent, so the CSV just ends
+	rc := http.NewResponseController(w)
+	cw := csv.NewWriter(w)
+	cw.Write(exportHeader)
+	for i, stored := range receipts {
+		if r.Context().Err() != nil {
+			break
+		}
+		cw.Write([]string{
+			stored.ID,
+			stored.Receipt.Retailer,
+			stored.Receipt.PurchaseDate,
+			stored.Receipt.PurchaseTime,
+			stored.Receipt.Total,
+			strconv.Itoa(stored.Points),
+		})
+		if (i+1)%exportFlushRows == 0 {
+			cw.Flush()
+			rc.Flush()
+		}
+		if cw.Error() != nil {
+			break
+		}
+	}
+	cw.Flush()
+}
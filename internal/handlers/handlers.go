@@ -2,96 +2,924 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"hash/fnv"
+	"io"
+	"log"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/gorilla/mux"
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/metrics"
 	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/store"
 	"github.com/saurabhag23/receipt-processor/internal/utils" // Import JWT helper for authentication
+	"github.com/saurabhag23/receipt-processor/internal/webhook"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
-	receipts = make(map[string]*models.ProcessedReceipt) // In-memory store for processed receipts
-	mu       sync.RWMutex                                 // Mutex for thread-safe access to receipts map
+	cfg               = config.Default()    // Active service configuration
+	clock utils.Clock = utils.SystemClock{} // Source of the processing time
 )
 
+// Handler serves the receipt endpoints from a receipt store; its methods are the
+// HTTP handlers. Keeping the store on a Handler rather than in a package variable
+// lets each server, or test, supply its own store implementation.
+type Handler struct {
+	store      store.ReceiptStore           // Store for processed receipts
+	ownerLocks [ownerLockStripes]sync.Mutex // Serialize one user's check-then-save steps; see ownerLock
+	lookups    singleflight.Group           // Coalesces concurrent store reads of the same receipt ID
+	notifier   *webhook.Notifier            // Receives an event for each newly stored receipt; nil disables webhooks
+}
+
+// ownerLockStripes is the number of locks the users are spread over. Users sharing
+// a stripe serialize their submissions; other users never wait on each other.
+const ownerLockStripes = 64
+
+// ownerLock returns the lock guarding owner's check-then-save steps. The duplicate,
+// per-retailer and first-purchase-of-day checks only consult the owner's own
+// receipts, so holding it is enough to keep them consistent with the save.
+func (h *Handler) ownerLock(owner string) *sync.Mutex {
+	f := fnv.New32a()
+	f.Write([]byte(owner))
+	return &h.ownerLocks[f.Sum32()%ownerLockStripes]
+}
+
+// NewHandler returns a Handler that keeps processed receipts in s.
+func NewHandler(s store.ReceiptStore) *Handler {
+	return &Handler{store: s}
+}
+
+// Configure replaces the active configuration used by the handlers.
+// It should be called once at startup, before the server begins accepting requests.
+func Configure(c config.Config) {
+	cfg = c
+}
+
+// UseWebhook sends an event to n for every receipt stored from now on.
+// It should be called once at startup, before the server begins accepting requests.
+func (h *Handler) UseWebhook(n *webhook.Notifier) {
+	h.notifier = n
+}
+
+// UseClock replaces the clock the handlers read the processing time from.
+// It should be called once at startup, before the server begins accepting requests.
+func UseClock(c utils.Clock) {
+	clock = c
+}
+
 // ProcessReceipt handles the POST request to process a receipt.
 // It validates the receipt, calculates points, generates a unique ID,
-// and stores it in memory.
-func ProcessReceipt(w http.ResponseWriter, r *http.Request) {
+// and stores it in memory. New receipts are answered with 201; resubmitting a
+// receipt that is already stored returns its existing ID with 200.
+func (h *Handler) ProcessReceipt(w http.ResponseWriter, r *http.Request) {
 	// Verify JWT token from Authorization header for secure access
-	if !utils.ValidateJWT(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Read the body, refusing overly nested payloads before decoding
+	body, ok := readJSONBody(w, r)
+	if !ok {
 		return
 	}
 
 	var receipt models.Receipt
 	// Parse JSON body into Receipt struct
-	if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+	if err := decodeReceipt(bytes.NewReader(body), &receipt); err != nil {
+		writeError(w, r, http.StatusBadRequest, decodeErrorMessage(err))
 		return
 	}
 
-	// Validate receipt data before processing
-	if err := validateReceipt(&receipt); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Time validation, scoring and storage; time.Since uses the monotonic clock
+	start := time.Now()
+
+	processedReceipt, created, err := h.processReceipt(r.Context(), &receipt, body, claims.Subject)
+	if err != nil {
+		writeError(w, r, errorStatus(err), err.Error())
 		return
 	}
 
+	// Respond with the generated receipt ID
+	resp := models.ProcessResponse{ID: processedReceipt.ID, ReceiptHash: processedReceipt.ReceiptHash, Warnings: processedReceipt.Warnings}
+	if cfg.DebugTiming {
+		elapsed := float64(time.Since(start).Microseconds()) / 1000
+		resp.ProcessingMs = &elapsed
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, r, status, resp)
+}
+
+// processReceipt validates a decoded receipt, calculates its points, and stores it
+// under a newly generated ID on behalf of owner, along with raw, the bytes it was
+// decoded from, when raw bodies are kept. If a receipt with the same content
+// hash is already stored, that receipt is returned instead and created is false.
+// Failures are returned as a *requestError carrying the HTTP status to respond with;
+// the receipt is not stored if ctx ends before the save.
+func (h *Handler) processReceipt(ctx context.Context, receipt *models.Receipt, raw []byte, owner string) (processed *models.ProcessedReceipt, created bool, err error) {
+	// Apply configured defaults and normalizations, then validate receipt data before processing
+	normalizeReceipt(receipt)
+	if err := validateReceipt(receipt); err != nil {
+		metrics.ValidationFailures.Inc()
+		return nil, false, &requestError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	// Run the configurable data-quality checks; warnings are stored with the receipt
+	warnings, err := runQualityChecks(receipt)
+	if err != nil {
+		metrics.ValidationFailures.Inc()
+		return nil, false, &requestError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	// Protected trademarks may only be submitted by allowlisted users
+	if err := checkReservedRetailer(receipt.Retailer, owner); err != nil {
+		return nil, false, &requestError{status: http.StatusForbidden, message: err.Error()}
+	}
+
 	// Calculate points based on receipt rules
-	points := calculatePoints(&receipt)
+	points, breakdown := calculatePoints(receipt, cfg.Rules)
 
-	// Generate a unique ID for the processed receipt
+	// Generate a unique ID for the processed receipt. The purchase moment is stored
+	// in UTC, with the offset it was written in, so date queries compare consistently
+	// across regions; it was already validated, so the error can be ignored.
 	id := uuid.New().String()
-	processedReceipt := &models.ProcessedReceipt{ID: id, Points: points}
+	now := clock.Now()
+	purchasedAt, _ := purchaseInstant(receipt)
+	processedReceipt := &models.ProcessedReceipt{
+		ID:             id,
+		Points:         points,
+		Owner:          owner,
+		Receipt:        *receipt,
+		Breakdown:      breakdown,
+		PurchasedAt:    purchasedAt.UTC(),
+		PurchaseOffset: purchasedAt.Format("-07:00"),
+		ProcessedAt:    now,
+		ModifiedAt:     now,
+		ReceiptHash:    receiptHash(receipt),
+		Warnings:       warnings,
+	}
+	if cfg.StoreRawBody {
+		processedReceipt.RawBody = raw
+	}
 
-	// Store the processed receipt in the in-memory store
+	// Store the processed receipt. The duplicate, per-retailer and first-purchase-of-day
+	// checks and the save run under the owner's lock so that concurrent identical
+	// receipts cannot both be stored, and concurrent same-day receipts from one user
+	// cannot both slip under the per-retailer limit or both be treated as the first.
+	mu := h.ownerLock(owner)
 	mu.Lock()
-	receipts[id] = processedReceipt
+	if existing, ok := h.store.GetByHash(owner, processedReceipt.ReceiptHash); ok {
+		mu.Unlock()
+		return existing, false, nil
+	}
+	if limit := cfg.MaxReceiptsPerRetailerPerDay; limit > 0 {
+		if h.countReceiptsAtRetailerOnDate(owner, receipt.Retailer, receipt.PurchaseDate) >= limit {
+			mu.Unlock()
+			return nil, false, &requestError{status: http.StatusForbidden, message: fmt.Sprintf("at most %d receipts per retailer per day are accepted", limit)}
+		}
+	}
+	if cfg.Rules.FirstPurchaseOfDayBonus != 0 && !h.hasEarlierReceiptOnDate(owner, receipt.PurchaseDate, receipt.PurchaseTime) {
+		processedReceipt.Points += cfg.Rules.FirstPurchaseOfDayBonus
+		processedReceipt.Breakdown = append(processedReceipt.Breakdown, firstPurchaseOfDayResult())
+	}
+	err = h.store.SaveContext(ctx, id, processedReceipt)
 	mu.Unlock()
+	if ctxErr := contextError(err); ctxErr != nil {
+		return nil, false, ctxErr
+	}
+	if err != nil {
+		log.Printf("failed to store receipt %s: %v", id, err)
+		return nil, false, &requestError{status: http.StatusInternalServerError, message: "Failed to store receipt"}
+	}
 
-	// Respond with the generated receipt ID
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"id": id})
+	// Counters cannot decrease, so receipts scoring below zero add no points
+	metrics.ReceiptsProcessed.Inc()
+	if processedReceipt.Points > 0 {
+		metrics.PointsAwarded.Add(float64(processedReceipt.Points))
+	}
+
+	// Tell the webhook receiver in the background; delivery never affects the response
+	h.notifier.Notify(webhook.Event{
+		Event:       "receipt.processed",
+		ID:          id,
+		Points:      processedReceipt.Points,
+		Retailer:    receipt.Retailer,
+		ProcessedAt: now,
+	})
+
+	return processedReceipt, true, nil
 }
 
 // GetPoints handles the GET request to retrieve points for a specific receipt.
 // It fetches the receipt by ID and returns the points awarded.
-func GetPoints(w http.ResponseWriter, r *http.Request) {
-	// Verify JWT token from Authorization header
-	if !utils.ValidateJWT(r) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+func (h *Handler) GetPoints(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	// Extract the receipt ID from the request URL
-	vars := mux.Vars(r)
-	id := vars["id"]
+	// Extract the receipt ID from the request URL, rejecting malformed IDs
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
 
-	// Safely retrieve receipt points with read-lock
-	mu.RLock()
-	receipt, exists := receipts[id]
-	mu.RUnlock()
+	// Safely retrieve receipt points from the store, sharing the read with concurrent requests for the same ID
+	receipt, exists, err := h.coalescedGet(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
 
 	// Handle case where receipt ID does not exist in the store
-	if !exists {
-		http.Error(w, "No receipt found for that ID", http.StatusNotFound)
+	if !exists || !canAccess(claims, receipt) {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+
+	// Include the points attributable to each item when requested, quoted when configured
+	resp := models.PointsResponse{Points: receipt.Points}
+	if r.URL.Query().Get("items") == "true" {
+		resp.Items = itemPoints(receipt.Breakdown)
+	}
+	var body interface{} = resp
+	if cfg.PointsAsString {
+		body = models.StringPointsResponse(resp)
+	}
+
+	// Let caching clients skip the body when the points have not changed
+	if notModifiedETag(w, r, pointsETag(id, body), receipt.ModifiedAt) {
 		return
 	}
 
 	// Send points in the response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]int{"points": receipt.Points})
+	writeJSON(w, r, http.StatusOK, body)
+}
+
+// GetPointsBatch handles the POST request to look up the points of many receipts at
+// once. The body is a JSON array of receipt IDs, read from the store as one
+// snapshot, and the response maps each ID to its points, or to null when no receipt
+// the caller may see is stored under it. Requests listing more IDs than the
+// configured maximum are rejected with 400.
+func (h *Handler) GetPointsBatch(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the JSON array of IDs
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var ids []string
+	if err := json.Unmarshal(body, &ids); err != nil {
+		writeError(w, r, http.StatusBadRequest, "Invalid JSON format: expected an array of receipt IDs")
+		return
+	}
+	if cfg.MaxLookupIDs > 0 && len(ids) > cfg.MaxLookupIDs {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("Lookup exceeds the maximum of %d IDs", cfg.MaxLookupIDs))
+		return
+	}
+
+	// Unknown IDs and receipts of other users are both reported as null
+	stored, err := h.store.GetManyContext(r.Context(), ids)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	points := make(map[string]*int, len(ids))
+	for _, id := range ids {
+		points[id] = nil
+		if receipt, exists := stored[id]; exists && canAccess(claims, receipt) {
+			p := receipt.Points
+			points[id] = &p
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, points)
+}
+
+// pointsETag returns the strong ETag of a points response body for the receipt
+// stored under id. It depends only on the ID and the encoded body, so it stays the
+// same across restarts of a durable store, and changes whenever the points, the
+// requested item detail or the points encoding do.
+func pointsETag(id string, body interface{}) string {
+	encoded, _ := json.Marshal(body)
+	sum := sha256.Sum256(append([]byte(id+"\n"), encoded...))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// storeLookup is the result of a store read shared by coalesced requests.
+type storeLookup struct {
+	receipt *models.ProcessedReceipt
+	exists  bool
+}
+
+// coalescedGet reads the receipt stored under id. When read coalescing is enabled,
+// concurrent calls for the same ID share a single store read instead of each
+// hitting the store. Stored receipts are immutable snapshots, so sharing the
+// pointer between callers is safe.
+//
+// The shared read ignores the cancellation of any one caller, so a client that
+// disconnects does not fail the others waiting on the same read.
+func (h *Handler) coalescedGet(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	if !cfg.CoalesceReads {
+		return h.store.GetContext(ctx, id)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	v, err, _ := h.lookups.Do(id, func() (interface{}, error) {
+		receipt, exists, err := h.store.GetContext(context.WithoutCancel(ctx), id)
+		return storeLookup{receipt: receipt, exists: exists}, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	lookup := v.(storeLookup)
+	return lookup.receipt, lookup.exists, nil
+}
+
+// GetReceipt handles the GET request to retrieve a stored receipt.
+// It returns the original receipt fields together with the ID and points awarded.
+func (h *Handler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Retrieve the receipt from the store, rejecting malformed IDs
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	receipt, exists, err := h.store.GetContext(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	if !exists || !canAccess(claims, receipt) {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+
+	// Let caching clients skip the body when the receipt has not changed
+	if notModified(w, r, receipt.ModifiedAt) {
+		return
+	}
+
+	resp := models.ReceiptResponse{
+		ID:             receipt.ID,
+		Receipt:        receipt.Receipt,
+		Points:         receipt.Points,
+		ReceiptHash:    receipt.ReceiptHash,
+		TopItem:        topItem(receipt.Receipt.Items),
+		PurchaseOffset: receipt.PurchaseOffset,
+		ProcessedAt:    receipt.ProcessedAt,
+	}
+	if !receipt.PurchasedAt.IsZero() {
+		resp.PurchasedAt = &receipt.PurchasedAt
+	}
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// canAccess reports whether the caller identified by claims may read or remove the
+// stored receipt: admins may access every receipt, other users only the receipts
+// they submitted. Callers answer with 404 rather than 403 for receipts of other
+// users, so that receipt IDs cannot be probed for existence.
+func canAccess(claims *utils.Claims, receipt *models.ProcessedReceipt) bool {
+	return claims.Role == utils.RoleAdmin || (claims.Subject != "" && receipt.Owner == claims.Subject)
+}
+
+// topItem returns the highest-priced item, comparing exact cents. Ties go to the
+// item that appears first, and items with unparseable prices are skipped.
+func topItem(items []models.Item) *models.Item {
+	var top *models.Item
+	var topPrice int64
+	for i := range items {
+		price, err := utils.ParseCents(items[i].Price)
+		if err != nil {
+			continue
+		}
+		if top == nil || price > topPrice {
+			top, topPrice = &items[i], price
+		}
+	}
+	return top
+}
+
+// DeleteReceipt handles the DELETE request to remove a stored receipt.
+// It responds 204 on success and 404 when the ID is unknown or, for users other
+// than admins, belongs to another user.
+func (h *Handler) DeleteReceipt(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Remove the receipt from the store, rejecting malformed IDs and receipts of other users
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	receipt, exists, err := h.store.GetContext(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	if !exists || !canAccess(claims, receipt) {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+	if err := h.store.DeleteContext(r.Context(), id); err != nil {
+		if ctxErr := contextError(err); ctxErr != nil {
+			writeError(w, r, errorStatus(ctxErr), ctxErr.Error())
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+			return
+		}
+		log.Printf("failed to delete receipt %s: %v", id, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to delete receipt")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetBreakdown handles the GET request to explain a receipt's points.
+// It returns the list of rules that awarded points, which sums to the receipt's total.
+func (h *Handler) GetBreakdown(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Retrieve the receipt from the store, rejecting malformed IDs
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	receipt, exists, err := h.store.GetContext(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	if !exists || !canAccess(claims, receipt) {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+
+	// Always send an array, even when no rule awarded points
+	breakdown := receipt.Breakdown
+	if breakdown == nil {
+		breakdown = []models.RuleResult{}
+	}
+	writeJSON(w, r, http.StatusOK, breakdown)
+}
+
+const (
+	defaultPageSize = 50  // Page size used when the client does not supply a limit
+	maxPageSize     = 500 // Largest page size a client may request
+)
+
+// ListReceipts handles the GET request to list processed receipts.
+// Results are ordered by insertion and paginated with an opaque cursor, so
+// receipts added or removed mid-scan never shift the pages already handed out.
+// An offset may be given instead of (or after) the cursor, and the optional
+// retailer and minPoints filters are applied before paginating. The response
+// reports the total number of receipts matching the filters.
+func (h *Handler) ListReceipts(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header
+	if !utils.ValidateJWT(r) {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the page size, falling back to the default when absent
+	limit := defaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			writeError(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageSize))
+			return
+		}
+		limit = n
+	}
+
+	// Decode the cursor into the sequence number of the last receipt already returned
+	var after uint64
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		seq, err := decodeCursor(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		after = seq
+	}
+
+	// Parse the number of matching receipts to skip after the cursor
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	// Parse the optional filters
+	retailer := r.URL.Query().Get("retailer")
+	minPoints := 0
+	if v := r.URL.Query().Get("minPoints"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, "minPoints must be an integer")
+			return
+		}
+		minPoints = n
+	}
+
+	// Parse the optional processing time window
+	from, to, ok := parseTimeWindow(w, r)
+	if !ok {
+		return
+	}
+
+	// Collect receipts matching the filters, counting them all and keeping those past the cursor
+	total := 0
+	var page []*models.ProcessedReceipt
+	if err := h.store.RangeContext(r.Context(), func(stored *models.ProcessedReceipt) bool {
+		if retailer != "" && !strings.EqualFold(stored.Receipt.Retailer, retailer) {
+			return true
+		}
+		if stored.Points < minPoints {
+			return true
+		}
+		if !processedWithin(stored, from, to) {
+			return true
+		}
+		total++
+		if stored.Sequence > after {
+			page = append(page, stored)
+		}
+		return true
+	}); err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+
+	sort.Slice(page, func(i, j int) bool { return page[i].Sequence < page[j].Sequence })
+	page = page[min(offset, len(page)):]
+
+	// Only hand out a next cursor when there are more receipts to fetch
+	nextCursor := ""
+	if len(page) > limit {
+		page = page[:limit]
+		nextCursor = encodeCursor(page[limit-1].Sequence)
+	}
+
+	summaries := make([]models.ReceiptSummary, 0, len(page))
+	for _, stored := range page {
+		summaries = append(summaries, models.ReceiptSummary{
+			ID:           stored.ID,
+			Retailer:     stored.Receipt.Retailer,
+			PurchaseDate: stored.Receipt.PurchaseDate,
+			Total:        stored.Receipt.Total,
+			Points:       stored.Points,
+			ProcessedAt:  stored.ProcessedAt,
+		})
+	}
+
+	// Send the page in the response
+	writeJSON(w, r, http.StatusOK, models.ReceiptListResponse{Receipts: summaries, NextCursor: nextCursor, Total: total})
+}
+
+// parseTimeWindow parses the optional from and to query parameters bounding the
+// processing time of receipts; either may be the zero time when absent. On invalid
+// values it writes a 400 response and returns false.
+func parseTimeWindow(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	if from, ok = parseTimeParam(w, r, "from"); !ok {
+		return
+	}
+	if to, ok = parseTimeParam(w, r, "to"); !ok {
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		writeError(w, r, http.StatusBadRequest, "to must be later than from")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// processedWithin reports whether stored was processed at or after from and before
+// to, where a zero from or to leaves that side of the window open.
+func processedWithin(stored *models.ProcessedReceipt, from, to time.Time) bool {
+	return (from.IsZero() || !stored.ProcessedAt.Before(from)) && (to.IsZero() || stored.ProcessedAt.Before(to))
+}
+
+// parseTimeParam parses the RFC 3339 query parameter name, returning the zero time
+// when it is absent. On a malformed value it writes a 400 response and returns false.
+func parseTimeParam(w http.ResponseWriter, r *http.Request, name string) (time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be an RFC 3339 timestamp", name))
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// encodeCursor turns an insertion sequence number into an opaque pagination cursor.
+func encodeCursor(seq uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(seq, 10)))
+}
+
+// decodeCursor reverses encodeCursor, rejecting cursors that were not issued by it.
+func decodeCursor(cursor string) (uint64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(raw), 10, 64)
+}
+
+// decodeReceipt parses a JSON receipt from body. When field aliases are configured,
+// aliased field names on the receipt and its items are renamed to their canonical
+// names before the payload is unmarshaled, and when numeric amounts are accepted,
+// numeric totals and prices are converted to amount strings.
+func decodeReceipt(body io.Reader, receipt *models.Receipt) error {
+	if len(cfg.FieldAliases) == 0 && !cfg.AllowNumericAmounts {
+		return decodeStrict(body, receipt)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		return err
+	}
+	applyFieldAliases(raw)
+	if err := convertNumericAmount(raw, "total"); err != nil {
+		return err
+	}
+
+	// Rename fields inside each item as well, leaving malformed items for the decoder to reject
+	if itemsJSON, ok := raw["items"]; ok {
+		var items []map[string]json.RawMessage
+		if err := json.Unmarshal(itemsJSON, &items); err == nil {
+			for _, item := range items {
+				applyFieldAliases(item)
+				if err := convertNumericAmount(item, "price"); err != nil {
+					return err
+				}
+			}
+			if encoded, err := json.Marshal(items); err == nil {
+				raw["items"] = encoded
+			}
+		}
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(bytes.NewReader(normalized), receipt)
+}
+
+// decodeStrict decodes JSON from body into receipt, failing on fields the receipt
+// does not define so that misspelled fields are reported rather than left empty.
+func decodeStrict(body io.Reader, receipt *models.Receipt) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(receipt)
+}
+
+// convertNumericAmount replaces a JSON number in fields[name] with the equivalent
+// amount string, rounded to cents by utils.RoundCents under the configured half-cent
+// policy, so 35.349999 becomes "35.35". Strings and other values are left for the
+// decoder and validation to check. Nothing is converted unless numeric amounts are
+// accepted.
+func convertNumericAmount(fields map[string]json.RawMessage, name string) error {
+	value, ok := fields[name]
+	if !cfg.AllowNumericAmounts || !ok || len(value) == 0 || (value[0] != '-' && (value[0] < '0' || value[0] > '9')) {
+		return nil
+	}
+	cents, err := utils.RoundCents(string(value), cfg.NumericHalfCent)
+	if err != nil {
+		return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf("invalid %s: %v", name, err)}
+	}
+	fields[name], _ = json.Marshal(utils.FormatCents(cents))
+	return nil
+}
+
+// decodeErrorMessage describes a receipt decoding failure, naming the offending
+// field when the body contained one the receipt does not define.
+func decodeErrorMessage(err error) string {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.message
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return "Unknown field " + field
+	}
+	return "Invalid JSON format"
+}
+
+// applyFieldAliases renames aliased keys in fields to their canonical names.
+// A canonical field that is already present takes precedence over its alias.
+func applyFieldAliases(fields map[string]json.RawMessage) {
+	for alias, canonical := range cfg.FieldAliases {
+		value, ok := fields[alias]
+		if !ok {
+			continue
+		}
+		delete(fields, alias)
+		if _, exists := fields[canonical]; !exists {
+			fields[canonical] = value
+		}
+	}
 }
 
+// purchaseInstant returns the moment a receipt was purchased. Its date and time are
+// read in the receipt's own timezone, or in the configured default zone when it
+// declares none; a receipt allowed to omit its time is taken to be purchased at
+// midnight. Malformed timezones, dates and times are reported as validation errors.
+func purchaseInstant(r *models.Receipt) (time.Time, error) {
+	zone := cfg.DefaultTimezone
+	if zone == nil {
+		zone = time.Local
+	}
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil || r.Timezone == "Local" {
+			return time.Time{}, fmt.Errorf("invalid timezone %q: expected an IANA name such as America/New_York", r.Timezone)
+		}
+		zone = loc
+	}
+
+	// Validate date format (expected YYYY-MM-DD)
+	date, err := time.ParseInLocation("2006-01-02", r.PurchaseDate, zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid purchase date format")
+	}
+
+	// Validate time format (expected HH:MM in 24-hour format). A receipt allowed to
+	// omit its time simply earns no points from the time-of-day rule.
+	if r.PurchaseTime == "" {
+		return date, nil
+	}
+	t, err := time.Parse("15:04", r.PurchaseTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid purchase time format")
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, zone), nil
+}
+
+// firstPurchaseOfDayRule names the first-purchase-of-day bonus in breakdowns. It is
+// awarded outside calculatePoints because it depends on the receipts already stored.
+const firstPurchaseOfDayRule = "firstPurchaseOfDay"
+
+// firstPurchaseOfDayResult returns the breakdown entry for the first-purchase-of-day bonus.
+func firstPurchaseOfDayResult() models.RuleResult {
+	return models.RuleResult{
+		Rule:        firstPurchaseOfDayRule,
+		Points:      cfg.Rules.FirstPurchaseOfDayBonus,
+		Description: "Bonus points for the user's earliest receipt on its purchase date",
+	}
+}
+
+// hasEarlierReceiptOnDate reports whether owner already has a stored receipt purchased
+// on the given date before a receipt purchased at purchaseTime, as ordered by
+// purchasedBefore. Only owner's receipts for that date are read, through the store's
+// date index. Callers must hold owner's lock.
+func (h *Handler) hasEarlierReceiptOnDate(owner, date, purchaseTime string) bool {
+	for _, stored := range h.store.GetByDate(owner, date) {
+		if purchasedBefore(stored.Receipt.PurchaseTime, purchaseTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// purchasedBefore reports whether a stored receipt purchased at storedTime comes
+// before a new receipt purchased at purchaseTime on the same day. Earlier times come
+// first; purchase times share the HH:MM layout, so they compare lexically. A receipt
+// without a purchase time cannot be placed within the day, so it comes after every
+// receipt that has one. Equal times, including two missing ones, are ordered by
+// processing order, in which the stored receipt always comes first.
+func purchasedBefore(storedTime, purchaseTime string) bool {
+	switch {
+	case storedTime == purchaseTime:
+		return true
+	case storedTime == "" || purchaseTime == "":
+		return purchaseTime == ""
+	default:
+		return storedTime < purchaseTime
+	}
+}
+
+// countReceiptsAtRetailerOnDate counts owner's stored receipts from retailer purchased
+// on the given date. Only owner's receipts for that date are read, through the
+// store's date index, and retailer names are compared ignoring case and surrounding
+// whitespace. Callers must hold owner's lock.
+func (h *Handler) countReceiptsAtRetailerOnDate(owner, retailer, date string) int {
+	retailer = strings.TrimSpace(retailer)
+	count := 0
+	for _, stored := range h.store.GetByDate(owner, date) {
+		if strings.EqualFold(strings.TrimSpace(stored.Receipt.Retailer), retailer) {
+			count++
+		}
+	}
+	return count
+}
+
+// normalizeReceipt fills in configured defaults for missing fields before validation.
+// With the default configuration it leaves the receipt untouched.
+func normalizeReceipt(r *models.Receipt) {
+	if r.Retailer == "" && cfg.DefaultRetailer != "" {
+		r.Retailer = cfg.DefaultRetailer
+	}
+
+	// Truncate seconds-precision times (HH:MM:SS) to the HH:MM layout used for scoring
+	if cfg.AllowPurchaseTimeSeconds {
+		if t, err := time.Parse("15:04:05", r.PurchaseTime); err == nil {
+			r.PurchaseTime = t.Format("15:04")
+		}
+	}
+
+	// Add the missing integer part to amounts such as ".50"
+	if cfg.AllowMissingLeadingZero {
+		r.Total = addLeadingZero(r.Total)
+		for i := range r.Items {
+			r.Items[i].Price = addLeadingZero(r.Items[i].Price)
+		}
+	}
+}
+
+// addLeadingZero turns an amount with no integer part, such as ".50" or "-.50",
+// into "0.50" or "-0.50".
+func addLeadingZero(amount string) string {
+	if strings.HasPrefix(amount, ".") {
+		return "0" + amount
+	}
+	if rest, ok := strings.CutPrefix(amount, "-."); ok {
+		return "-0." + rest
+	}
+	return amount
+}
+
+// Formats of the receipt fields checked by pattern in validateReceipt and
+// validateItem. The OpenAPI document publishes the same expressions. Go's \w is
+// ASCII-only, so international letters, combining marks and digits are listed
+// explicitly.
+const (
+	retailerFormat    = `^[\p{L}\p{M}\p{Nd}_\s\-&]+$`
+	descriptionFormat = `^[\p{L}\p{M}\p{Nd}_\s\-]+$`
+	totalFormat       = `^\d+\.\d{2}$`   // 0.00
+	priceFormat       = `^-?\d+\.\d{2}$` // 0.00, or -0.00 for a discount or coupon
+)
+
+var (
+	retailerRegex    = regexp.MustCompile(retailerFormat)
+	descriptionRegex = regexp.MustCompile(descriptionFormat)
+	totalRegex       = regexp.MustCompile(totalFormat)
+	priceRegex       = regexp.MustCompile(priceFormat)
+)
+
 // validateReceipt performs validation on the receipt data, ensuring required fields
 // are present and correctly formatted.
 func validateReceipt(r *models.Receipt) error {
@@ -102,7 +930,7 @@ func validateReceipt(r *models.Receipt) error {
 	if r.PurchaseDate == "" {
 		return fmt.Errorf("purchaseDate is required")
 	}
-	if r.PurchaseTime == "" {
+	if r.PurchaseTime == "" && !cfg.AllowMissingTime {
 		return fmt.Errorf("purchaseTime is required")
 	}
 	if len(r.Items) == 0 {
@@ -113,27 +941,40 @@ func validateReceipt(r *models.Receipt) error {
 	}
 
 	// Regular expression validations for specific fields
-	retailerRegex := regexp.MustCompile(`^[\w\s\-&]+$`)
 	if !retailerRegex.MatchString(r.Retailer) {
 		return fmt.Errorf("invalid retailer name format")
 	}
 
-	// Validate date format (expected YYYY-MM-DD)
-	if _, err := time.Parse("2006-01-02", r.PurchaseDate); err != nil {
-		return fmt.Errorf("invalid purchase date format")
+	// Validate the optional timezone and the purchase date and time read in it
+	purchasedAt, err := purchaseInstant(r)
+	if err != nil {
+		return err
 	}
 
-	// Validate time format (expected HH:MM in 24-hour format)
-	if _, err := time.Parse("15:04", r.PurchaseTime); err != nil {
-		return fmt.Errorf("invalid purchase time format")
+	// Reject purchases later than the server's clock, allowing for some clock skew,
+	// and, when a maximum age is configured, purchases too old to be submitted
+	now := clock.Now()
+	if cfg.RejectFuturePurchases && purchasedAt.After(now.Add(cfg.FuturePurchaseTolerance)) {
+		return fmt.Errorf("purchase date/time cannot be in the future")
+	}
+	if cfg.MaxPurchaseAge > 0 && now.Sub(purchasedAt) > cfg.MaxPurchaseAge {
+		return fmt.Errorf("purchase date/time is older than the maximum age of %s", cfg.MaxPurchaseAge)
 	}
 
 	// Validate total amount format (expected 0.00)
-	totalRegex := regexp.MustCompile(`^\d+\.\d{2}$`)
 	if !totalRegex.MatchString(r.Total) {
 		return fmt.Errorf("invalid total format")
 	}
 
+	// Validate the optional currency code, normalizing numeric codes to alphabetic ones
+	if r.Currency != "" {
+		currency, err := utils.NormalizeCurrency(r.Currency)
+		if err != nil {
+			return fmt.Errorf("invalid currency code")
+		}
+		r.Currency = currency
+	}
+
 	// Validate each item in the receipt
 	for _, item := range r.Items {
 		if err := validateItem(&item); err != nil {
@@ -144,6 +985,24 @@ func validateReceipt(r *models.Receipt) error {
 	return nil
 }
 
+// checkReservedRetailer rejects retailer names containing a configured reserved
+// trademark, unless the submitting user is on the reserved retailer allowlist.
+func checkReservedRetailer(retailer, subject string) error {
+	for _, allowed := range cfg.ReservedRetailerAllowedUsers {
+		if subject == allowed {
+			return nil
+		}
+	}
+
+	name := strings.ToLower(retailer)
+	for _, reserved := range cfg.ReservedRetailers {
+		if strings.Contains(name, strings.ToLower(reserved)) {
+			return fmt.Errorf("retailer name %q is reserved", reserved)
+		}
+	}
+	return nil
+}
+
 // validateItem validates individual item data in the receipt, checking for
 // required fields and proper formatting.
 func validateItem(i *models.Item) error {
@@ -156,98 +1015,14 @@ func validateItem(i *models.Item) error {
 	}
 
 	// Validate description format
-	descRegex := regexp.MustCompile(`^[\w\s\-]+$`)
-	if !descRegex.MatchString(i.ShortDescription) {
+	if !descriptionRegex.MatchString(i.ShortDescription) {
 		return fmt.Errorf("invalid item short description format")
 	}
 
-	// Validate price format (expected 0.00)
-	priceRegex := regexp.MustCompile(`^\d+\.\d{2}$`)
+	// Validate price format (expected 0.00, or -0.00 for a discount or coupon)
 	if !priceRegex.MatchString(i.Price) {
 		return fmt.Errorf("invalid item price format")
 	}
 
 	return nil
 }
-
-// calculatePoints calculates the points for the receipt based on predefined rules.
-func calculatePoints(r *models.Receipt) int {
-	points := 0
-
-	// Rule 1: One point per alphanumeric character in retailer name
-	points += countAlphanumeric(r.Retailer)
-
-	// Rule 2: 50 points if the total is a round dollar amount
-	if strings.HasSuffix(r.Total, ".00") {
-		points += 50
-	}
-
-	// Rule 3: 25 points if the total is a multiple of 0.25
-	if isTotalMultipleOf25Cents(r.Total) {
-		points += 25
-	}
-
-	// Rule 4: 5 points for every two items
-	points += (len(r.Items) / 2) * 5
-
-	// Rule 5: Extra points if item description length is multiple of 3
-	for _, item := range r.Items {
-		if len(strings.TrimSpace(item.ShortDescription))%3 == 0 {
-			price, _ := strconv.ParseFloat(item.Price, 64)
-			points += int(math.Ceil(price * 0.2))
-		}
-	}
-
-	// Rule 6: 6 points if purchase day is odd
-	if isPurchaseDateOdd(r.PurchaseDate) {
-		points += 6
-	}
-
-	// Rule 7: 10 points if purchase time is between 2:00pm and 4:00pm
-	if isPurchaseTimeBetween2And4PM(r.PurchaseTime) {
-		points += 10
-	}
-
-	return points
-}
-
-// Helper functions for calculating points
-
-// countAlphanumeric counts alphanumeric characters in a string.
-func countAlphanumeric(s string) int {
-	count := 0
-	for _, char := range s {
-		if (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') {
-			count++
-		}
-	}
-	return count
-}
-
-// isTotalMultipleOf25Cents checks if the total is a multiple of 0.25.
-func isTotalMultipleOf25Cents(total string) bool {
-	f, err := strconv.ParseFloat(total, 64)
-	if err != nil {
-		return false
-	}
-	cents := int(f * 100)
-	return cents%25 == 0
-}
-
-// isPurchaseDateOdd checks if the purchase date day is odd.
-func isPurchaseDateOdd(date string) bool {
-	t, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		return false
-	}
-	return t.Day()%2 != 0
-}
-
-// isPurchaseTimeBetween2And4PM checks if purchase time is between 2:00pm and 4:00pm.
-func isPurchaseTimeBetween2And4PM(timeStr string) bool {
-	t, err := time.Parse("15:04", timeStr)
-	if err != nil {
-		return false
-	}
-	return t.Hour() >= 14 && t.Hour() < 16
-}
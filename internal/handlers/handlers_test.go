@@ -0,0 +1,1128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/middleware"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+	"github.com/saurabhag23/receipt-processor/internal/webhook"
+)
+
+// targetReceipt is the example receipt from the README, worth 28 points under the
+// default rules.
+const targetReceipt = `{
+	"retailer": "Target",
+	"purchaseDate": "2022-01-01",
+	"purchaseTime": "13:01",
+	"items": [
+		{"shortDescription": "Mountain Dew 12PK", "price": "6.49"},
+		{"shortDescription": "Emils Cheese Pizza", "price": "12.25"},
+		{"shortDescription": "Knorr Creamy Chicken", "price": "1.26"},
+		{"shortDescription": "Doritos Nacho Cheese", "price": "3.35"},
+		{"shortDescription": "   Klarbrunn 12-PK 12 FL OZ  ", "price": "12.00"}
+	],
+	"total": "35.35"
+}`
+
+// newTestHandler returns a Handler over an empty in-memory store, running with the
+// default configuration adjusted by configure. The previous configuration and
+// clock are restored when the test ends.
+func newTestHandler(t testing.TB, configure func(*config.Config)) *Handler {
+	t.Helper()
+	previous, previousClock := cfg, clock
+	t.Cleanup(func() { cfg, clock = previous, previousClock })
+
+	c := config.Default()
+	if configure != nil {
+		configure(&c)
+	}
+	cfg = c
+	return NewHandler(store.NewInMemoryStore())
+}
+
+// authorize adds a bearer token for user to r.
+func authorize(t testing.TB, r *http.Request, user string) {
+	t.Helper()
+	token, err := utils.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("GenerateJWT: %v", err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+}
+
+// postReceipt submits body to ProcessReceipt on behalf of user.
+func postReceipt(t testing.TB, h *Handler, user, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/v1/receipts/process", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	authorize(t, r, user)
+	w := httptest.NewRecorder()
+	h.ProcessReceipt(w, r)
+	return w
+}
+
+// mustProcess submits body on behalf of user, failing the test unless it is
+// answered with wantStatus, and returns the receipt ID.
+func mustProcess(t testing.TB, h *Handler, user, body string, wantStatus int) string {
+	t.Helper()
+	w := postReceipt(t, h, user, body)
+	if w.Code != wantStatus {
+		t.Fatalf("process as %s: status %d (%s), want %d", user, w.Code, strings.TrimSpace(w.Body.String()), wantStatus)
+	}
+	var resp models.ProcessResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode process response: %v", err)
+	}
+	return resp.ID
+}
+
+// getPoints requests the points of receipt id on behalf of user.
+func getPoints(t testing.TB, h *Handler, user, id string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v1/receipts/"+id+"/points", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	authorize(t, r, user)
+	w := httptest.NewRecorder()
+	h.GetPoints(w, r)
+	return w
+}
+
+// withReceipt returns targetReceipt with the top-level field set to value.
+func withReceipt(t testing.TB, field string, value interface{}) string {
+	t.Helper()
+	var receipt map[string]interface{}
+	if err := json.Unmarshal([]byte(targetReceipt), &receipt); err != nil {
+		t.Fatal(err)
+	}
+	receipt[field] = value
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestProcessReceiptDuplicatesAreScopedToTheOwner(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	aliceID := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	bobID := mustProcess(t, h, "bob", targetReceipt, http.StatusCreated)
+	if bobID == aliceID {
+		t.Fatalf("bob was given alice's receipt ID %s", aliceID)
+	}
+
+	// Each user sees their own copy and only that
+	if w := getPoints(t, h, "bob", bobID); w.Code != http.StatusOK {
+		t.Errorf("bob reading his receipt: status %d, want 200", w.Code)
+	}
+	if w := getPoints(t, h, "bob", aliceID); w.Code != http.StatusNotFound {
+		t.Errorf("bob reading alice's receipt: status %d, want 404", w.Code)
+	}
+
+	// Resubmissions are still recognized per user
+	if id := mustProcess(t, h, "alice", targetReceipt, http.StatusOK); id != aliceID {
+		t.Errorf("alice resubmitting: got ID %s, want %s", id, aliceID)
+	}
+	if id := mustProcess(t, h, "bob", targetReceipt, http.StatusOK); id != bobID {
+		t.Errorf("bob resubmitting: got ID %s, want %s", id, bobID)
+	}
+}
+
+func TestProcessReceiptResubmissionReturnsExistingID(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	if again := mustProcess(t, h, "alice", targetReceipt, http.StatusOK); again != id {
+		t.Errorf("resubmission: got ID %s, want %s", again, id)
+	}
+
+	other := mustProcess(t, h, "alice", withReceipt(t, "purchaseTime", "14:30"), http.StatusCreated)
+	if other == id {
+		t.Errorf("new receipt was given the existing ID %s", id)
+	}
+}
+
+func TestProcessReceiptDoesNotWaitForOtherUsers(t *testing.T) {
+	h := newTestHandler(t, nil)
+
+	// Hold alice's lock as if one of her submissions were mid-save, then submit as a
+	// user on another stripe
+	mu := h.ownerLock("alice")
+	other := "bob"
+	for i := 0; h.ownerLock(other) == mu; i++ {
+		other = fmt.Sprintf("bob%d", i)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	done := make(chan int, 1)
+	go func() { done <- postReceipt(t, h, other, targetReceipt).Code }()
+	select {
+	case code := <-done:
+		if code != http.StatusCreated {
+			t.Errorf("status %d, want 201", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s's submission waited on alice's lock", other)
+	}
+}
+
+// pointsOf returns the points of receipt id as seen by user, failing the test
+// unless the lookup succeeds.
+func pointsOf(t testing.TB, h *Handler, user, id string) int {
+	t.Helper()
+	w := getPoints(t, h, user, id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("points of %s as %s: status %d (%s)", id, user, w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	var resp models.PointsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode points response: %v", err)
+	}
+	return resp.Points
+}
+
+func TestFirstPurchaseOfDayBonus(t *testing.T) {
+	const bonus = 100
+	h := newTestHandler(t, func(c *config.Config) {
+		c.Rules.FirstPurchaseOfDayBonus = bonus
+		c.AllowMissingTime = true
+	})
+
+	first := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	if got := pointsOf(t, h, "alice", first); got != 28+bonus {
+		t.Errorf("first receipt of the day: %d points, want %d", got, 28+bonus)
+	}
+
+	// 16:30 is after the 2pm-4pm window, so the base score stays 28
+	later := withReceipt(t, "purchaseTime", "16:30")
+	if got := pointsOf(t, h, "alice", mustProcess(t, h, "alice", later, http.StatusCreated)); got != 28 {
+		t.Errorf("later receipt the same day: %d points, want 28", got)
+	}
+	if got := pointsOf(t, h, "bob", mustProcess(t, h, "bob", later, http.StatusCreated)); got != 28+bonus {
+		t.Errorf("another user's first receipt that day: %d points, want %d", got, 28+bonus)
+	}
+
+	nextDay := withReceipt(t, "purchaseDate", "2022-01-03")
+	if got := pointsOf(t, h, "alice", mustProcess(t, h, "alice", nextDay, http.StatusCreated)); got != 28+bonus {
+		t.Errorf("first receipt of the next day: %d points, want %d", got, 28+bonus)
+	}
+
+	// Receipts at the same minute go by processing order, and receipts without a
+	// purchase time come after every receipt that has one
+	at := func(retailer, purchaseTime string) string {
+		body := strings.Replace(withReceipt(t, "purchaseTime", purchaseTime), `"Target"`, `"`+retailer+`"`, 1)
+		if purchaseTime == "" {
+			body = strings.Replace(body, `"purchaseTime":"",`, "", 1)
+		}
+		return body
+	}
+	for _, step := range []struct {
+		user, retailer, purchaseTime string
+		bonus                        bool
+	}{
+		{"carol", "Target", "12:00", true},
+		{"carol", "Walmart", "12:00", false}, // Same minute, processed second
+		{"carol", "Walgreens", "11:59", true},
+		{"dave", "Target", "", true},
+		{"dave", "Walmart", "09:00", true}, // The receipt without a time may have been later
+		{"dave", "Walgreens", "", false},
+		{"erin", "Target", "09:00", true},
+		{"erin", "Walmart", "", false},
+	} {
+		id := mustProcess(t, h, step.user, at(step.retailer, step.purchaseTime), http.StatusCreated)
+		stored, _ := h.store.Get(id)
+		got := false
+		for _, result := range stored.Breakdown {
+			got = got || result.Rule == firstPurchaseOfDayRule
+		}
+		if got != step.bonus {
+			t.Errorf("%s at %s purchased at %q: bonus %v, want %v", step.user, step.retailer, step.purchaseTime, got, step.bonus)
+		}
+	}
+}
+
+func TestMaxReceiptsPerRetailerPerDay(t *testing.T) {
+	h := newTestHandler(t, func(c *config.Config) { c.MaxReceiptsPerRetailerPerDay = 2 })
+
+	// Receipts differ only in purchase time, so none is a duplicate of another
+	at := func(date, purchaseTime string) string {
+		var receipt map[string]interface{}
+		if err := json.Unmarshal([]byte(withReceipt(t, "purchaseDate", date)), &receipt); err != nil {
+			t.Fatal(err)
+		}
+		receipt["purchaseTime"] = purchaseTime
+		body, _ := json.Marshal(receipt)
+		return string(body)
+	}
+
+	mustProcess(t, h, "alice", at("2022-01-01", "10:00"), http.StatusCreated)
+	mustProcess(t, h, "alice", at("2022-01-01", "11:00"), http.StatusCreated)
+	if w := postReceipt(t, h, "alice", at("2022-01-01", "12:00")); w.Code != http.StatusForbidden {
+		t.Errorf("third receipt from Target that day: status %d, want 403", w.Code)
+	}
+
+	// The limit is per retailer, per user and per day
+	otherRetailer := strings.Replace(at("2022-01-01", "12:00"), `"Target"`, `"Walgreens"`, 1)
+	mustProcess(t, h, "alice", otherRetailer, http.StatusCreated)
+	mustProcess(t, h, "bob", at("2022-01-01", "12:00"), http.StatusCreated)
+	mustProcess(t, h, "alice", at("2022-01-02", "10:00"), http.StatusCreated)
+}
+
+// fixedClock is a utils.Clock stopped at one instant.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestCampaignBonusFollowsTheClock(t *testing.T) {
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * 24 * time.Hour)
+	h := newTestHandler(t, func(c *config.Config) {
+		c.Rules.CampaignBonus = 100
+		c.Rules.CampaignStart = start
+		c.Rules.CampaignEnd = end
+	})
+
+	// Capture the webhook event so its timestamp can be checked too
+	events := make(chan webhook.Event, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e webhook.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	defer receiver.Close()
+	h.UseWebhook(webhook.New(receiver.URL, "secret", 0, 0, time.Second))
+
+	for i, tc := range []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{"start", start, 22 + 100},
+		{"middle", start.Add(end.Sub(start) / 2), 22 + 50},
+		{"end", end, 22},
+	} {
+		UseClock(fixedClock(tc.now))
+		// Distinct even purchase dates, which miss the odd-day points and score 22 without the campaign
+		body := withReceipt(t, "purchaseDate", fmt.Sprintf("2022-01-%02d", 2*(i+1)))
+		id := mustProcess(t, h, "alice", body, http.StatusCreated)
+		if got := pointsOf(t, h, "alice", id); got != tc.want {
+			t.Errorf("%s of the campaign: %d points, want %d", tc.name, got, tc.want)
+		}
+
+		stored, _ := h.store.Get(id)
+		if !stored.ProcessedAt.Equal(tc.now) || !stored.ModifiedAt.Equal(tc.now) {
+			t.Errorf("%s: processedAt %s, modifiedAt %s; want the clock's %s", tc.name, stored.ProcessedAt, stored.ModifiedAt, tc.now)
+		}
+		select {
+		case e := <-events:
+			if !e.ProcessedAt.Equal(tc.now) {
+				t.Errorf("%s: webhook processedAt %s, want %s", tc.name, e.ProcessedAt, tc.now)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: no webhook event", tc.name)
+		}
+	}
+}
+
+func TestCancelledRequestsAreAbandoned(t *testing.T) {
+	h := newTestHandler(t, nil)
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"cancelled", cancelled, statusClientClosedRequest},
+		{"timed out", expired, http.StatusServiceUnavailable},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/v1/receipts/points", strings.NewReader(`["`+id+`"]`)).WithContext(tc.ctx)
+		r.Header.Set("Content-Type", "application/json")
+		authorize(t, r, "alice")
+		w := httptest.NewRecorder()
+		h.GetPointsBatch(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s batch lookup: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+
+		r = httptest.NewRequest(http.MethodDelete, "/v1/receipts/"+id, nil).WithContext(tc.ctx)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		authorize(t, r, "alice")
+		w = httptest.NewRecorder()
+		h.DeleteReceipt(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s delete: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+
+	// Neither abandoned delete removed the receipt
+	if got := pointsOf(t, h, "alice", id); got != 28 {
+		t.Errorf("points after abandoned deletes = %d, want 28", got)
+	}
+}
+
+func TestConcurrentRecalculationAndReads(t *testing.T) {
+	for name, s := range map[string]store.ReceiptStore{
+		"memory":  store.NewInMemoryStore(),
+		"sharded": store.NewShardedStore(4, 0),
+	} {
+		t.Run(name, func(t *testing.T) {
+			newTestHandler(t, nil)
+			h := NewHandler(s)
+			id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						if _, err := h.recalculate(id); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						if _, err := h.store.Update(id, func(r *models.ProcessedReceipt) { r.Warnings = []string{"checked"} }); err != nil {
+							t.Error(err)
+							return
+						}
+					}
+				}()
+			}
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 50; j++ {
+						if w := getPoints(t, h, "alice", id); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"points":28`) {
+							t.Errorf("points during recalculation: status %d, body %s", w.Code, w.Body.String())
+							return
+						}
+						stored, _ := h.store.Get(id)
+						sum := 0
+						for _, result := range stored.Breakdown {
+							sum += result.Points
+						}
+						if sum != stored.Points {
+							t.Errorf("breakdown sums to %d, points are %d", sum, stored.Points)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestFirstPurchaseOfDayBonusIsNotMultiplied(t *testing.T) {
+	h := newTestHandler(t, func(c *config.Config) {
+		c.Rules.FirstPurchaseOfDayBonus = 100
+		c.Rules.MonthMultipliers = map[time.Month]float64{time.December: 1.5}
+	})
+
+	// 22 points on an even day, multiplied to 33, then the bonus on top
+	id := mustProcess(t, h, "alice", withReceipt(t, "purchaseDate", "2022-12-02"), http.StatusCreated)
+	if got := pointsOf(t, h, "alice", id); got != 33+100 {
+		t.Errorf("points = %d, want %d", got, 33+100)
+	}
+}
+
+// countingStore is a store whose GetContext calls are counted and, once gate is
+// set, held until it is closed, so that concurrent readers pile up behind one read.
+type countingStore struct {
+	store.ReceiptStore
+	reads   atomic.Int32
+	entered chan struct{} // Receives a value as each read starts
+	gate    chan struct{} // Closed to let held reads finish
+}
+
+func (s *countingStore) GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	s.reads.Add(1)
+	s.entered <- struct{}{}
+	<-s.gate
+	return s.ReceiptStore.GetContext(ctx, id)
+}
+
+func TestCoalescedReadsShareOneStoreRead(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		coalesce bool
+		want     int32
+	}{
+		{"coalesced", true, 1},
+		{"uncoalesced", false, 8},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestHandler(t, func(c *config.Config) { c.CoalesceReads = tc.coalesce })
+			mem := store.NewInMemoryStore()
+			id := mustProcess(t, NewHandler(mem), "alice", targetReceipt, http.StatusCreated)
+
+			const readers = 8
+			s := &countingStore{ReceiptStore: mem, entered: make(chan struct{}, readers), gate: make(chan struct{})}
+			h := NewHandler(s)
+
+			codes := make(chan int, readers)
+			go func() { codes <- getPoints(t, h, "alice", id).Code }()
+			<-s.entered // The first read is now in flight and held
+			for i := 1; i < readers; i++ {
+				go func() { codes <- getPoints(t, h, "alice", id).Code }()
+			}
+			if !tc.coalesce {
+				for i := 1; i < readers; i++ {
+					<-s.entered
+				}
+			}
+			// Give the remaining readers time to join the read in flight
+			time.Sleep(50 * time.Millisecond)
+			close(s.gate)
+
+			for i := 0; i < readers; i++ {
+				if code := <-codes; code != http.StatusOK {
+					t.Errorf("reader got status %d, want 200", code)
+				}
+			}
+			if got := s.reads.Load(); got != tc.want {
+				t.Errorf("%d concurrent readers made %d store reads, want %d", readers, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResponsesAreByteStable(t *testing.T) {
+	const hash = "1bf03c4543c97aa319e06bffed3d465f255a0839ad4efb4b0182f4b58c9d6b10"
+	points := func(t *testing.T, h *Handler, id, query string) string {
+		r := httptest.NewRequest(http.MethodGet, "/v1/receipts/"+id+"/points"+query, nil)
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		authorize(t, r, "alice")
+		w := httptest.NewRecorder()
+		h.GetPoints(w, r)
+		return w.Body.String()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		h := newTestHandler(t, nil)
+		w := postReceipt(t, h, "alice", targetReceipt)
+		var resp models.ProcessResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+
+		// The ID is random; everything around it is fixed
+		if want := `{"id":"` + resp.ID + `","receiptHash":"` + hash + `"}` + "\n"; w.Body.String() != want {
+			t.Errorf("process response:\n got %q\nwant %q", w.Body.String(), want)
+		}
+		if got, want := points(t, h, resp.ID, ""), `{"points":28}`+"\n"; got != want {
+			t.Errorf("points response:\n got %q\nwant %q", got, want)
+		}
+		if got, want := points(t, h, resp.ID, "?items=true"), `{"points":28,"items":{"1":3,"4":3}}`+"\n"; got != want {
+			t.Errorf("points response with items:\n got %q\nwant %q", got, want)
+		}
+	})
+
+	t.Run("points as string", func(t *testing.T) {
+		h := newTestHandler(t, func(c *config.Config) { c.PointsAsString = true })
+		id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+		if got, want := points(t, h, id, ""), `{"points":"28"}`+"\n"; got != want {
+			t.Errorf("points response:\n got %q\nwant %q", got, want)
+		}
+	})
+}
+
+func TestNumericTotalsRoundToCents(t *testing.T) {
+	for _, tc := range []struct {
+		total    string // Sent as a bare JSON number
+		halfCent string
+		want     string // Stored total, or empty when the receipt is rejected
+	}{
+		{"35.344", utils.HalfCentReject, "35.34"},
+		{"35.344", utils.HalfCentUp, "35.34"},
+		{"35.344", utils.HalfCentEven, "35.34"},
+		{"35.345", utils.HalfCentReject, ""},
+		{"35.345", utils.HalfCentUp, "35.35"},
+		{"35.345", utils.HalfCentEven, "35.34"},
+	} {
+		t.Run(tc.total+"/"+tc.halfCent, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) {
+				c.AllowNumericAmounts = true
+				c.NumericHalfCent = tc.halfCent
+			})
+			body := withReceipt(t, "total", json.Number(tc.total))
+			if tc.want == "" {
+				if w := postReceipt(t, h, "alice", body); w.Code != http.StatusBadRequest {
+					t.Errorf("status %d, want 400 for a total on a half cent", w.Code)
+				}
+				return
+			}
+			id := mustProcess(t, h, "alice", body, http.StatusCreated)
+			if stored, _ := h.store.Get(id); stored.Receipt.Total != tc.want {
+				t.Errorf("stored total = %q, want %q", stored.Receipt.Total, tc.want)
+			}
+		})
+	}
+}
+
+// stalledWriter is a response writer whose first Write blocks until release is
+// closed, like a client that has stopped reading.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{} // Closed when the first Write starts
+	release chan struct{}
+	once    sync.Once
+}
+
+func (w *stalledWriter) Write(b []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestExportDoesNotBlockSavesWhileTheClientStalls(t *testing.T) {
+	h := newTestHandler(t, nil)
+	// More receipts than one flush covers, so rows are written before the export ends
+	for i := 0; i < 2*exportFlushRows; i++ {
+		id := fmt.Sprintf("r%d", i)
+		if err := h.store.Save(id, &models.ProcessedReceipt{ID: id, Owner: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &stalledWriter{ResponseRecorder: httptest.NewRecorder(), writing: make(chan struct{}), release: make(chan struct{})}
+	r := httptest.NewRequest(http.MethodGet, "/v1/receipts/export", nil)
+	authorize(t, r, "admin")
+	exported := make(chan struct{})
+	go func() {
+		defer close(exported)
+		h.ExportReceipts(w, r)
+	}()
+	<-w.writing
+
+	saved := make(chan error, 1)
+	go func() { saved <- h.store.Save("late", &models.ProcessedReceipt{ID: "late", Owner: "bob"}) }()
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Save blocked while the export waited on the client")
+	}
+
+	close(w.release)
+	<-exported
+	if rows := strings.Count(w.Body.String(), "\n"); rows != 2*exportFlushRows+1 {
+		t.Errorf("export has %d lines, want a header and %d rows", rows, 2*exportFlushRows)
+	}
+}
+
+func TestNonUTCReceiptIsStoredInUTCAndScoredLocally(t *testing.T) {
+	h := newTestHandler(t, nil)
+	for _, tc := range []struct {
+		time        string
+		purchasedAt string
+		points      int
+	}{
+		// 28 points, plus the afternoon bonus for 14:30 New York time though it is 19:30 in UTC
+		{"14:30", "2022-01-01T19:30:00Z", 38},
+		// Still January 1, an odd day, on the receipt's calendar though January 2 in UTC
+		{"23:30", "2022-01-02T04:30:00Z", 28},
+	} {
+		body := strings.Replace(targetReceipt, `"purchaseTime": "13:01"`, `"purchaseTime": "`+tc.time+`", "timezone": "America/New_York"`, 1)
+		id := mustProcess(t, h, "alice", body, http.StatusCreated)
+
+		stored, _ := h.store.Get(id)
+		if got := stored.PurchasedAt.Format(time.RFC3339); got != tc.purchasedAt || stored.PurchasedAt.Location() != time.UTC {
+			t.Errorf("%s: purchasedAt = %s in %s, want %s", tc.time, got, stored.PurchasedAt.Location(), tc.purchasedAt)
+		}
+		if stored.PurchaseOffset != "-05:00" {
+			t.Errorf("%s: purchaseOffset = %q, want -05:00", tc.time, stored.PurchaseOffset)
+		}
+		if stored.Receipt.PurchaseDate != "2022-01-01" || stored.Receipt.PurchaseTime != tc.time {
+			t.Errorf("%s: stored local purchase %s %s, want it as written", tc.time, stored.Receipt.PurchaseDate, stored.Receipt.PurchaseTime)
+		}
+		if got := pointsOf(t, h, "alice", id); got != tc.points {
+			t.Errorf("%s: points = %d, want %d", tc.time, got, tc.points)
+		}
+	}
+}
+
+func TestFieldAliases(t *testing.T) {
+	aliases := map[string]string{"store": "retailer", "date": "purchaseDate", "description": "shortDescription", "amount": "price"}
+	aliased := strings.NewReplacer(`"retailer"`, `"store"`, `"purchaseDate"`, `"date"`, `"shortDescription"`, `"description"`, `"price"`, `"amount"`).Replace(targetReceipt)
+
+	for _, tc := range []struct {
+		name    string
+		aliases map[string]string
+		body    string
+		want    int
+	}{
+		{"aliased names", aliases, aliased, http.StatusCreated},
+		{"canonical names alongside aliases", aliases, targetReceipt, http.StatusCreated},
+		{"canonical name takes precedence", aliases, strings.Replace(targetReceipt, `"retailer": "Target",`, `"retailer": "Target", "store": "Walgreens",`, 1), http.StatusCreated},
+		{"aliases not configured", nil, aliased, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.FieldAliases = tc.aliases })
+			w := postReceipt(t, h, "alice", tc.body)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want != http.StatusCreated {
+				return
+			}
+			var resp models.ProcessResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			stored, _ := h.store.Get(resp.ID)
+			if stored.Receipt.Retailer != "Target" || stored.Receipt.PurchaseDate != "2022-01-01" || stored.Receipt.Items[1].Price != "12.25" {
+				t.Errorf("stored receipt = %+v, want targetReceipt's fields", stored.Receipt)
+			}
+			if got := pointsOf(t, h, "alice", resp.ID); got != 28 {
+				t.Errorf("points = %d, want 28", got)
+			}
+		})
+	}
+}
+
+// listPage fetches one page of the receipt listing with the given query string.
+func listPage(t testing.TB, h *Handler, query string) models.ReceiptListResponse {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v1/receipts?"+query, nil)
+	authorize(t, r, "alice")
+	w := httptest.NewRecorder()
+	h.ListReceipts(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list %q: status %d, body %q", query, w.Code, w.Body.String())
+	}
+	var resp models.ReceiptListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestCursorPagingIsStableAcrossConcurrentChanges(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		change func(t *testing.T, h *Handler, ids []string) []string // Makes the change, returning the IDs a full walk should now see
+	}{
+		{"insert", func(t *testing.T, h *Handler, ids []string) []string {
+			return append(ids, mustProcess(t, h, "late", targetReceipt, http.StatusCreated))
+		}},
+		{"delete of a receipt already returned", func(t *testing.T, h *Handler, ids []string) []string {
+			if err := h.store.Delete(ids[0]); err != nil {
+				t.Fatal(err)
+			}
+			return ids
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, nil)
+			var ids []string
+			for i := 0; i < 5; i++ {
+				ids = append(ids, mustProcess(t, h, fmt.Sprintf("user%d", i), targetReceipt, http.StatusCreated))
+			}
+
+			page := listPage(t, h, "limit=2")
+			seen := []string{}
+			for _, s := range page.Receipts {
+				seen = append(seen, s.ID)
+			}
+			want := tc.change(t, h, ids)
+			for page.NextCursor != "" {
+				page = listPage(t, h, "limit=2&cursor="+page.NextCursor)
+				for _, s := range page.Receipts {
+					seen = append(seen, s.ID)
+				}
+			}
+			if strings.Join(seen, ",") != strings.Join(want, ",") {
+				t.Errorf("paged through %v, want %v", seen, want)
+			}
+		})
+	}
+}
+
+func TestEnvelopedAndRawResponses(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		envelope bool
+		known    bool // Whether the points of a stored receipt are requested
+		status   int
+		raw      string // Body without the envelope
+		data     string // Enveloped data or error, with meta checked separately
+	}{
+		{"raw success", false, true, http.StatusOK, `{"points":28}` + "\n", ""},
+		{"raw error", false, false, http.StatusNotFound, "No receipt found for that ID\n", ""},
+		{"enveloped success", true, true, http.StatusOK, "", `{"points":28}`},
+		{"enveloped error", true, false, http.StatusNotFound, "", `"No receipt found for that ID"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, nil)
+			id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+			cfg.Envelope = tc.envelope
+			if !tc.known {
+				id = "00000000-0000-0000-0000-000000000000"
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/receipts/"+id+"/points", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": id})
+			r.Header.Set("X-Request-ID", "req-1")
+			authorize(t, r, "alice")
+			w := httptest.NewRecorder()
+			middleware.RequestID(http.HandlerFunc(h.GetPoints)).ServeHTTP(w, r)
+			if w.Code != tc.status {
+				t.Fatalf("status %d, want %d", w.Code, tc.status)
+			}
+
+			if !tc.envelope {
+				if got := w.Body.String(); got != tc.raw {
+					t.Errorf("body = %q, want %q", got, tc.raw)
+				}
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+			var env struct {
+				Data  json.RawMessage
+				Error json.RawMessage
+				Meta  struct{ RequestID string }
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+				t.Fatal(err)
+			}
+			got := env.Data
+			if tc.status != http.StatusOK {
+				got = env.Error
+			}
+			if string(got) != tc.data || (tc.status == http.StatusOK) != (env.Error == nil) {
+				t.Errorf("envelope = %s, want %s as its only data or error", w.Body.String(), tc.data)
+			}
+			if env.Meta.RequestID != "req-1" {
+				t.Errorf("meta.requestId = %q, want req-1", env.Meta.RequestID)
+			}
+		})
+	}
+}
+
+func TestReservedRetailerNames(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		user     string
+		retailer string
+		want     int
+	}{
+		{"reserved name from an allowed user", "target-corp", "Target", http.StatusCreated},
+		{"reserved name from another user", "alice", "Target", http.StatusForbidden},
+		{"reserved name in another case", "alice", "SuperTARGET Store", http.StatusForbidden},
+		{"unreserved name from another user", "alice", "Walgreens", http.StatusCreated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) {
+				c.ReservedRetailers = []string{"target"}
+				c.ReservedRetailerAllowedUsers = []string{"target-corp"}
+			})
+			w := postReceipt(t, h, tc.user, withReceipt(t, "retailer", tc.retailer))
+			if w.Code != tc.want {
+				t.Errorf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecalculationStampsModifiedAtFromTheClock(t *testing.T) {
+	h := newTestHandler(t, nil)
+	processed := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock = fixedClock(processed)
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+
+	recalculated := processed.Add(time.Hour)
+	clock = fixedClock(recalculated)
+	if _, err := h.recalculate(id); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := h.store.Get(id)
+	if !stored.ProcessedAt.Equal(processed) || !stored.ModifiedAt.Equal(recalculated) {
+		t.Errorf("processedAt %s, modifiedAt %s; want %s and %s", stored.ProcessedAt, stored.ModifiedAt, processed, recalculated)
+	}
+}
+
+// getReceipt requests the full receipt id on behalf of user, sending ifModifiedSince
+// as If-Modified-Since when it is not empty.
+func getReceipt(t testing.TB, h *Handler, user, id, ifModifiedSince string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/v1/receipts/"+id, nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	if ifModifiedSince != "" {
+		r.Header.Set("If-Modified-Since", ifModifiedSince)
+	}
+	authorize(t, r, user)
+	w := httptest.NewRecorder()
+	h.GetReceipt(w, r)
+	return w
+}
+
+func TestReceiptConditionalGet(t *testing.T) {
+	h := newTestHandler(t, nil)
+	processed := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock = fixedClock(processed)
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+
+	w := getReceipt(t, h, "alice", id, "")
+	lastModified := w.Header().Get("Last-Modified")
+	if w.Code != http.StatusOK || lastModified != processed.Format(http.TimeFormat) {
+		t.Fatalf("status %d, Last-Modified %q; want 200 and %q", w.Code, lastModified, processed.Format(http.TimeFormat))
+	}
+
+	for _, tc := range []struct {
+		name            string
+		ifModifiedSince string
+		want            int
+	}{
+		{"since the last modification", lastModified, http.StatusNotModified},
+		{"since later", processed.Add(time.Minute).Format(http.TimeFormat), http.StatusNotModified},
+		{"since earlier", processed.Add(-time.Second).Format(http.TimeFormat), http.StatusOK},
+		{"malformed date", "yesterday", http.StatusOK},
+	} {
+		if w := getReceipt(t, h, "alice", id, tc.ifModifiedSince); w.Code != tc.want {
+			t.Errorf("before mutation, %s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+	}
+
+	// Rescoring the receipt modifies it, so the old Last-Modified no longer matches
+	rescored := processed.Add(time.Hour)
+	clock = fixedClock(rescored)
+	if _, err := h.recalculate(id); err != nil {
+		t.Fatal(err)
+	}
+	w = getReceipt(t, h, "alice", id, lastModified)
+	if w.Code != http.StatusOK || w.Header().Get("Last-Modified") != rescored.Format(http.TimeFormat) {
+		t.Errorf("after mutation: status %d, Last-Modified %q; want 200 and %q", w.Code, w.Header().Get("Last-Modified"), rescored.Format(http.TimeFormat))
+	}
+	if w := getReceipt(t, h, "alice", id, rescored.Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Errorf("after mutation, since the new modification: status %d, want 304", w.Code)
+	}
+}
+
+func TestPerItemPoints(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		configure func(*config.Config)
+		points    int
+		items     map[int]int
+	}{
+		{"default rules", nil, 28, map[int]int{1: 3, 4: 3}},
+		{"mixed item rules", func(c *config.Config) {
+			c.Rules.EvenCentsItemPoints = 1
+			c.Rules.RoundDollarItemPoints = 2
+			c.Rules.DigitDescriptionPoints = 4
+		}, 40, map[int]int{
+			0: 4,             // "Mountain Dew 12PK": a digit
+			1: 3,             // "Emils Cheese Pizza": description length 18
+			2: 1,             // 1.26: even cents
+			4: 3 + 1 + 2 + 4, // "Klarbrunn 12-PK 12 FL OZ" at 12.00: every item rule
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, tc.configure)
+			id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/receipts/"+id+"/points?items=true", nil)
+			r = mux.SetURLVars(r, map[string]string{"id": id})
+			authorize(t, r, "alice")
+			w := httptest.NewRecorder()
+			h.GetPoints(w, r)
+			var resp models.PointsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("status %d, body %q: %v", w.Code, w.Body.String(), err)
+			}
+			if resp.Points != tc.points {
+				t.Errorf("points = %d, want %d", resp.Points, tc.points)
+			}
+			if len(resp.Items) != len(tc.items) {
+				t.Errorf("items = %v, want %v", resp.Items, tc.items)
+			}
+			for idx, want := range tc.items {
+				if got := resp.Items[idx]; got != want {
+					t.Errorf("item %d: %d points, want %d", idx, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMissingLeadingZero(t *testing.T) {
+	for _, allow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow=%v", allow), func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.AllowMissingLeadingZero = allow })
+
+			w := postReceipt(t, h, "alice", withReceipt(t, "total", ".50"))
+			if !allow {
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("status %d (%s), want 400", w.Code, strings.TrimSpace(w.Body.String()))
+				}
+				return
+			}
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d (%s), want 201", w.Code, strings.TrimSpace(w.Body.String()))
+			}
+			var resp models.ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			// 0.50 is a multiple of 0.25, worth 25 points on top of targetReceipt's 28
+			if got := pointsOf(t, h, "alice", resp.ID); got != 53 {
+				t.Errorf("points = %d, want 53", got)
+			}
+			stored, ok := h.store.Get(resp.ID)
+			if !ok {
+				t.Fatalf("receipt %s was not stored", resp.ID)
+			}
+			if stored.Receipt.Total != "0.50" {
+				t.Errorf("stored total = %q, want 0.50", stored.Receipt.Total)
+			}
+		})
+	}
+}
+
+func TestMissingPurchaseTime(t *testing.T) {
+	body := strings.Replace(targetReceipt, `"purchaseTime": "13:01",`, "", 1)
+	for _, allow := range []bool{false, true} {
+		t.Run(fmt.Sprintf("allow=%v", allow), func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) {
+				c.AllowMissingTime = allow
+				c.DefaultTimezone = time.UTC
+			})
+
+			w := postReceipt(t, h, "alice", body)
+			if !allow {
+				if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "purchaseTime is required") {
+					t.Fatalf("status %d (%s), want 400 requiring purchaseTime", w.Code, strings.TrimSpace(w.Body.String()))
+				}
+				return
+			}
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d (%s), want 201", w.Code, strings.TrimSpace(w.Body.String()))
+			}
+			var resp models.ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			// The receipt is dated at midnight and earns nothing from the time-of-day rule
+			if got := pointsOf(t, h, "alice", resp.ID); got != 28 {
+				t.Errorf("points = %d, want 28", got)
+			}
+			stored, ok := h.store.Get(resp.ID)
+			if !ok {
+				t.Fatalf("receipt %s was not stored", resp.ID)
+			}
+			if want := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC); !stored.PurchasedAt.Equal(want) {
+				t.Errorf("purchasedAt = %v, want %v", stored.PurchasedAt, want)
+			}
+		})
+	}
+}
+
+func TestRawBodyIsStoredByteForByte(t *testing.T) {
+	// Layout, key order and escapes that a re-encoded receipt would not reproduce
+	body := "{\"total\":\"35.35\",  \"retailer\": \"T\\u0061rget\",\n\t\"purchaseDate\": \"2022-01-01\", \"purchaseTime\": \"13:01\",\r\n" +
+		"\"items\": [{\"shortDescription\": \"Mountain Dew 12PK\", \"price\": \"6.49\"}] }\n"
+	for _, keep := range []bool{false, true} {
+		t.Run(fmt.Sprintf("store=%v", keep), func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.StoreRawBody = keep })
+			id := mustProcess(t, h, "alice", body, http.StatusCreated)
+			stored, ok := h.store.Get(id)
+			if !ok {
+				t.Fatalf("receipt %s was not stored", id)
+			}
+			if keep && string(stored.RawBody) != body {
+				t.Errorf("raw body = %q, want %q", stored.RawBody, body)
+			}
+			if !keep && stored.RawBody != nil {
+				t.Errorf("raw body %q stored with the setting off", stored.RawBody)
+			}
+		})
+	}
+}
+
+func TestProcessingTimeIsReportedWhenDebugTimingIsOn(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		t.Run(fmt.Sprintf("debug=%v", debug), func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.DebugTiming = debug })
+			w := postReceipt(t, h, "alice", targetReceipt)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status %d (%s), want 201", w.Code, strings.TrimSpace(w.Body.String()))
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			ms, ok := resp["processingMs"].(float64)
+			if ok != debug || ms < 0 {
+				t.Errorf("processingMs = %v (present %v), want present %v and not negative", resp["processingMs"], ok, debug)
+			}
+
+			// Timing belongs to the request that did the work, not to the stored receipt
+			got := getReceipt(t, h, "alice", resp["id"].(string), "")
+			if got.Code != http.StatusOK || strings.Contains(got.Body.String(), "processingMs") {
+				t.Errorf("full receipt: status %d, body %s", got.Code, got.Body.String())
+			}
+		})
+	}
+}
+
+func TestFullReceiptReportsTheTopItem(t *testing.T) {
+	h := newTestHandler(t, nil)
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	w := getReceipt(t, h, "alice", id, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d (%s), want 200", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	var resp models.ReceiptResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.Item{ShortDescription: "Emils Cheese Pizza", Price: "12.25"}); resp.TopItem == nil || *resp.TopItem != want {
+		t.Errorf("topItem = %+v, want %+v", resp.TopItem, want)
+	}
+
+	for _, tc := range []struct {
+		prices []string
+		want   int // Index of the top item, or -1 for none
+	}{
+		{nil, -1},
+		{[]string{"1.00", "12.00", "9.99"}, 1},
+		{[]string{"5.00", "5.00"}, 0},  // Ties go to the first
+		{[]string{"10.00", "9.00"}, 0}, // Compared as amounts, not strings
+		{[]string{"abc", "-1.00"}, 1},
+		{[]string{"abc"}, -1},
+	} {
+		items := itemsPriced(tc.prices...)
+		got := topItem(items)
+		if (tc.want < 0 && got != nil) || (tc.want >= 0 && got != &items[tc.want]) {
+			t.Errorf("topItem(%v) = %+v, want index %d", tc.prices, got, tc.want)
+		}
+	}
+}
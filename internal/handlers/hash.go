@@ -0,0 +1,66 @@
+// hash.go
+// This file computes the canonical hash of a receipt, which lets clients verify that
+// their stored copy of a receipt matches what the server processed.
+
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// receiptHash returns the hex-encoded SHA-256 of the canonical form of a receipt.
+// Semantically identical receipts hash the same regardless of JSON key order, item
+// order, surrounding whitespace, or amount formatting such as "1.5" versus "1.50".
+func receiptHash(r *models.Receipt) string {
+	// encoding/json writes map keys in sorted order, which makes the output canonical
+	items := make([]map[string]string, 0, len(r.Items))
+	for _, item := range r.Items {
+		items = append(items, map[string]string{
+			"shortDescription": strings.TrimSpace(item.ShortDescription),
+			"price":            canonicalAmount(item.Price),
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i]["shortDescription"] != items[j]["shortDescription"] {
+			return items[i]["shortDescription"] < items[j]["shortDescription"]
+		}
+		return items[i]["price"] < items[j]["price"]
+	})
+
+	fields := map[string]interface{}{
+		"retailer":     strings.TrimSpace(r.Retailer),
+		"purchaseDate": r.PurchaseDate,
+		"purchaseTime": r.PurchaseTime,
+		"items":        items,
+		"total":        canonicalAmount(r.Total),
+	}
+	// Optional fields only take part when present, keeping existing hashes stable
+	if r.Currency != "" {
+		fields["currency"] = r.Currency
+	}
+	if r.Timezone != "" {
+		fields["timezone"] = r.Timezone
+	}
+
+	canonical, _ := json.Marshal(fields)
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalAmount normalizes an amount through its exact cents value, leaving
+// amounts that cannot be parsed unchanged.
+func canonicalAmount(amount string) string {
+	cents, err := utils.ParseCents(amount)
+	if err != nil {
+		return amount
+	}
+	return utils.FormatCents(cents)
+}
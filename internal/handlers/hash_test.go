@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestReceiptHashIgnoresKeyOrder(t *testing.T) {
+	h := newTestHandler(t, nil)
+	hashOf := func(user, body string) string {
+		t.Helper()
+		w := postReceipt(t, h, user, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("status %d, body %q", w.Code, w.Body.String())
+		}
+		var resp struct{ ReceiptHash string }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.ReceiptHash
+	}
+
+	// targetReceipt with its fields, and those of each item, in reverse order
+	reordered := `{
+		"total": "35.35",
+		"items": [
+			{"price": "6.49", "shortDescription": "Mountain Dew 12PK"},
+			{"price": "12.25", "shortDescription": "Emils Cheese Pizza"},
+			{"price": "1.26", "shortDescription": "Knorr Creamy Chicken"},
+			{"price": "3.35", "shortDescription": "Doritos Nacho Cheese"},
+			{"price": "12.00", "shortDescription": "   Klarbrunn 12-PK 12 FL OZ  "}
+		],
+		"purchaseTime": "13:01",
+		"purchaseDate": "2022-01-01",
+		"retailer": "Target"
+	}`
+
+	// Each receipt is sent by its own user so none is answered as a resubmission
+	want := hashOf("alice", targetReceipt)
+	if got := hashOf("bob", reordered); got != want {
+		t.Errorf("hash with reordered keys = %s, want %s", got, want)
+	}
+	if got := hashOf("carol", withReceipt(t, "total", "35.36")); got == want {
+		t.Error("receipts with different totals hash the same")
+	}
+}
@@ -0,0 +1,56 @@
+// health.go
+// This file contains the health check handlers used by load balancers and
+// container orchestration. They do not require authentication.
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+)
+
+// Health statuses reported by the health check handlers
+const (
+	statusOK          = "ok"          // Process is up and the store responds promptly
+	statusDegraded    = "degraded"    // Store is reachable but slower than the configured threshold
+	statusUnavailable = "unavailable" // Store cannot be reached
+)
+
+// healthResponse is the body returned by the health check handlers.
+type healthResponse struct {
+	Status    string  `json:"status"`              // One of ok, degraded or unavailable
+	LatencyMs float64 `json:"latencyMs,omitempty"` // Time taken to ping the store
+	Error     string  `json:"error,omitempty"`     // Reason the store is unavailable
+}
+
+// Healthz handles the GET request for the health check. It responds 200 "ok" when
+// the receipt store can be pinged and 503 "unavailable" when it cannot, without
+// judging latency, so deployment tooling can tell a live process from a broken one.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Ping(); err != nil {
+		writeJSON(w, r, http.StatusServiceUnavailable, healthResponse{Status: statusUnavailable, Error: err.Error()})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, healthResponse{Status: statusOK})
+}
+
+// Readyz handles the GET request for the readiness check. It pings the receipt store
+// and responds 200 "ok" when the ping is fast, 200 "degraded" when it succeeds but
+// exceeds the configured latency threshold, and 503 "unavailable" when it fails.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	err := h.store.Ping()
+	latency := time.Since(start)
+
+	resp := healthResponse{Status: statusOK, LatencyMs: float64(latency.Microseconds()) / 1000}
+	status := http.StatusOK
+	switch {
+	case err != nil:
+		resp.Status, resp.Error = statusUnavailable, err.Error()
+		status = http.StatusServiceUnavailable
+	case cfg.ReadyDegradedLatency > 0 && latency > cfg.ReadyDegradedLatency:
+		resp.Status = statusDegraded
+	}
+
+	writeJSON(w, r, status, resp)
+}
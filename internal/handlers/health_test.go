@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+)
+
+// slowStore is a receipt store whose pings take delay and then fail with err.
+type slowStore struct {
+	store.ReceiptStore
+	delay time.Duration
+	err   error
+}
+
+func (s *slowStore) Ping() error {
+	time.Sleep(s.delay)
+	return s.err
+}
+
+func TestReadyz(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		delay      time.Duration
+		err        error
+		wantCode   int
+		wantStatus string
+	}{
+		{"fast", 0, nil, http.StatusOK, statusOK},
+		{"slow", 30 * time.Millisecond, nil, http.StatusOK, statusDegraded},
+		{"failing", 0, errors.New("store closed"), http.StatusServiceUnavailable, statusUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestHandler(t, func(c *config.Config) { c.ReadyDegradedLatency = 10 * time.Millisecond })
+			h := NewHandler(&slowStore{ReceiptStore: store.NewInMemoryStore(), delay: tc.delay, err: tc.err})
+
+			w := httptest.NewRecorder()
+			h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			var resp healthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tc.wantCode || resp.Status != tc.wantStatus {
+				t.Errorf("status %d %q, want %d %q", w.Code, resp.Status, tc.wantCode, tc.wantStatus)
+			}
+			if tc.delay > 0 && resp.LatencyMs < float64(tc.delay.Milliseconds()) {
+				t.Errorf("latencyMs = %v, want at least %d", resp.LatencyMs, tc.delay.Milliseconds())
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// openapi.go
+// This file serves the OpenAPI 3 description of the API, completing the embedded
+// document with JSON schemas generated from the models package types.
+
+package handlers
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// openAPIBase is the hand-written part of the document: paths, security and error
+// responses. Its components.schemas are generated by openAPISchemas.
+//
+//go:embed openapi.json
+var openAPIBase []byte
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument []byte // Completed document, built on first request once cfg is final
+)
+
+// OpenAPI handles the GET request for the OpenAPI document describing the receipt
+// processing and points endpoints. It is unauthenticated so that clients can
+// generate code from it.
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		doc, err := buildOpenAPI()
+		if err != nil {
+			log.Printf("failed to build OpenAPI document: %v", err)
+			return
+		}
+		openAPIDocument = doc
+	})
+	if openAPIDocument == nil {
+		writeError(w, r, http.StatusInternalServerError, "OpenAPI document unavailable")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// buildOpenAPI adds the generated schemas to the embedded document. The points
+// schema follows POINTS_AS_STRING and the receipt schemas follow the validation
+// settings, so the document matches the requests accepted and responses served.
+func buildOpenAPI() ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(openAPIBase, &doc); err != nil {
+		return nil, err
+	}
+
+	var points interface{} = models.PointsResponse{}
+	if cfg.PointsAsString {
+		points = models.StringPointsResponse{}
+	}
+	schemas := openAPISchemas(map[string]interface{}{
+		"Receipt":         models.Receipt{},
+		"ProcessResponse": models.ProcessResponse{},
+		"PointsResponse":  points,
+	})
+
+	describeReceiptFormats(schemas)
+
+	components, _ := doc["components"].(map[string]interface{})
+	if components == nil {
+		components = make(map[string]interface{})
+		doc["components"] = components
+	}
+	components["schemas"] = schemas
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPISchemas generates a schema for each named root value, and for the models
+// types they refer to, keyed by schema name.
+func openAPISchemas(roots map[string]interface{}) map[string]interface{} {
+	schemas := make(map[string]interface{})
+	for name, v := range roots {
+		schemas[name] = structSchema(reflect.TypeOf(v), schemas)
+	}
+	return schemas
+}
+
+// structSchema describes a struct as an object schema, following encoding/json's
+// rules: fields are named by their json tag, embedded structs contribute their
+// fields, and fields without omitempty are required.
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if field.Anonymous && tag == "" {
+				addFields(field.Type)
+				continue
+			}
+			if !field.IsExported() || tag == "-" {
+				continue
+			}
+			fieldName, opts, _ := strings.Cut(tag, ",")
+			if fieldName == "" {
+				fieldName = field.Name
+			}
+
+			schema := typeSchema(field.Type, schemas)
+			if hasTagOption(opts, "string") {
+				schema = map[string]interface{}{"type": "string", "pattern": `^-?\d+$`}
+			}
+			properties[fieldName] = schema
+			if !hasTagOption(opts, "omitempty") {
+				required = append(required, fieldName)
+			}
+		}
+	}
+	addFields(t)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// describeReceiptFormats adds what the Go types alone cannot express to the Receipt
+// and Item schemas: the formats validateReceipt and validateItem check, and which
+// fields the active configuration lets a receipt omit. Patterns are those the
+// validators use, relaxed where the receipt is normalized before validation.
+func describeReceiptFormats(schemas map[string]interface{}) {
+	receipt := schemas["Receipt"].(map[string]interface{})
+	item := schemas["Item"].(map[string]interface{})
+
+	// Dates and times are checked by parsing with the layouts 2006-01-02 and 15:04,
+	// which require a two-digit month, day and minute but accept a one-digit hour
+	timeFormat := `^\d{1,2}:\d{2}$`
+	if cfg.AllowPurchaseTimeSeconds {
+		timeFormat = `^\d{1,2}:\d{2}(:\d{2})?$`
+	}
+	total, price := totalFormat, priceFormat
+	if cfg.AllowMissingLeadingZero {
+		total, price = `^\d*\.\d{2}$`, `^-?\d*\.\d{2}$`
+	}
+
+	// Fields the configuration lets a receipt omit may also be sent empty
+	retailer := retailerFormat
+	var optional []string
+	if cfg.DefaultRetailer != "" {
+		retailer = orEmpty(retailer)
+		optional = append(optional, "retailer")
+	}
+	if cfg.AllowMissingTime {
+		timeFormat = orEmpty(timeFormat)
+		optional = append(optional, "purchaseTime")
+	}
+
+	setPattern(receipt, "retailer", retailer)
+	setPattern(receipt, "purchaseDate", `^\d{4}-\d{2}-\d{2}$`)
+	setPattern(receipt, "purchaseTime", timeFormat)
+	setAmount(receipt, "total", total)
+	setPattern(item, "shortDescription", descriptionFormat)
+	setAmount(item, "price", price)
+	receipt["properties"].(map[string]interface{})["items"].(map[string]interface{})["minItems"] = 1
+
+	// Currency codes are matched ignoring case and surrounding whitespace
+	codes := utils.CurrencyCodes()
+	for i, code := range codes {
+		codes[i] = caseInsensitivePattern(code)
+	}
+	setPattern(receipt, "currency", orEmpty(`^\s*(`+strings.Join(codes, "|")+`)\s*$`))
+
+	zone := cfg.DefaultTimezone
+	if zone == nil {
+		zone = time.Local
+	}
+	receipt["properties"].(map[string]interface{})["timezone"].(map[string]interface{})["description"] =
+		"IANA time zone name, such as America/New_York. Receipts without one are read in " + zone.String() + "."
+
+	// Unknown fields are rejected unless aliases may stand in for the canonical names
+	if len(cfg.FieldAliases) == 0 {
+		receipt["additionalProperties"] = false
+		item["additionalProperties"] = false
+	}
+
+	var required []string
+	for _, field := range receipt["required"].([]string) {
+		if !slices.Contains(optional, field) {
+			required = append(required, field)
+		}
+	}
+	receipt["required"] = required
+}
+
+// orEmpty extends pattern to also match the empty string.
+func orEmpty(pattern string) string {
+	return "^$|" + pattern
+}
+
+// setPattern sets the pattern of the named string property of an object schema.
+func setPattern(schema map[string]interface{}, property, pattern string) {
+	schema["properties"].(map[string]interface{})[property].(map[string]interface{})["pattern"] = pattern
+}
+
+// setAmount describes the named amount property of an object schema: a string
+// matching pattern or, when ALLOW_NUMERIC_AMOUNTS is on, a JSON number.
+func setAmount(schema map[string]interface{}, property, pattern string) {
+	amount := map[string]interface{}{"type": "string", "pattern": pattern}
+	if cfg.AllowNumericAmounts {
+		amount = map[string]interface{}{"oneOf": []interface{}{amount, map[string]interface{}{"type": "number"}}}
+	}
+	schema["properties"].(map[string]interface{})[property] = amount
+}
+
+// caseInsensitivePattern matches s ignoring the case of its ASCII letters, since
+// JSON Schema patterns have no case-insensitive flag.
+func caseInsensitivePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if lower, upper := unicode.ToLower(r), unicode.ToUpper(r); lower != upper {
+			b.WriteString("[" + string(upper) + string(lower) + "]")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// typeSchema describes a Go type. Named structs become references to their own
+// schema, which is generated the first time it is seen.
+func typeSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, seen := schemas[t.Name()]; !seen {
+			schemas[t.Name()] = nil // Reserve the name so recursive types terminate
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), schemas)}
+	case reflect.Map:
+		// JSON object keys are always strings, whatever the Go key type
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), schemas)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// hasTagOption reports whether the comma-separated json tag options include option.
+func hasTagOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+// schemaAccepts reports whether value satisfies schema, resolving references
+// against the document's components. It understands the subset of JSON Schema the
+// generated document uses.
+func schemaAccepts(t *testing.T, doc map[string]interface{}, schema map[string]interface{}, value interface{}) bool {
+	t.Helper()
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		return schemaAccepts(t, doc, schemas[name].(map[string]interface{}), value)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, alternative := range oneOf {
+			if schemaAccepts(t, doc, alternative.(map[string]interface{}), value) {
+				matches++
+			}
+		}
+		return matches == 1
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, field := range schema["required"].([]interface{}) {
+			if _, ok := object[field.(string)]; !ok {
+				return false
+			}
+		}
+		for field, v := range object {
+			property, ok := properties[field].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return false
+				}
+				continue
+			}
+			if !schemaAccepts(t, doc, property, v) {
+				return false
+			}
+		}
+		return true
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(array)) < minItems {
+			return false
+		}
+		for _, v := range array {
+			if !schemaAccepts(t, doc, schema["items"].(map[string]interface{}), v) {
+				return false
+			}
+		}
+		return true
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			return regexp.MustCompile(pattern).MatchString(s)
+		}
+		return true
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	}
+	t.Fatalf("unsupported schema %v", schema)
+	return false
+}
+
+// validatorAccepts reports whether the receipt in body passes decoding,
+// normalization and validation as ProcessReceipt applies them.
+func validatorAccepts(body string) bool {
+	var receipt models.Receipt
+	if err := decodeReceipt(strings.NewReader(body), &receipt); err != nil {
+		return false
+	}
+	normalizeReceipt(&receipt)
+	return validateReceipt(&receipt) == nil
+}
+
+func TestOpenAPIReceiptSchemaMatchesValidation(t *testing.T) {
+	// Samples are targetReceipt with one field replaced; a nil value removes it
+	samples := []struct {
+		field string
+		value interface{}
+	}{
+		{"total", "35.35"},
+		{"total", "-35.35"},
+		{"total", "35.3"},
+		{"total", ".35"},
+		{"total", 35.35},
+		{"total", ""},
+		{"total", nil},
+		{"retailer", "M&M Corner Market"},
+		{"retailer", "Café München"},
+		{"retailer", "Target!"},
+		{"retailer", ""},
+		{"retailer", nil},
+		{"purchaseDate", "2022-01-01"},
+		{"purchaseDate", "2022-1-01"},
+		{"purchaseDate", "01/01/2022"},
+		{"purchaseTime", "13:01"},
+		{"purchaseTime", "9:05"},
+		{"purchaseTime", "13:01:30"},
+		{"purchaseTime", "1301"},
+		{"purchaseTime", ""},
+		{"purchaseTime", nil},
+		{"currency", "usd"},
+		{"currency", " 840 "},
+		{"currency", "XYZ"},
+		{"items", []interface{}{}},
+		{"items", []interface{}{map[string]interface{}{"shortDescription": "Pepsi 12-PK", "price": "-1.25"}}},
+		{"items", []interface{}{map[string]interface{}{"shortDescription": "Pepsi!", "price": "1.25"}}},
+		{"items", []interface{}{map[string]interface{}{"shortDescription": "Pepsi", "price": "1.2"}}},
+		{"items", []interface{}{map[string]interface{}{"shortDescription": "Pepsi", "price": 1.25}}},
+		{"items", []interface{}{map[string]interface{}{"shortDescription": "Pepsi", "price": "1.25", "sku": "1"}}},
+		{"cashier", "Sam"},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		configure func(*config.Config)
+	}{
+		{"default", nil},
+		{"lenient", func(c *config.Config) {
+			c.AllowMissingTime = true
+			c.AllowPurchaseTimeSeconds = true
+			c.AllowMissingLeadingZero = true
+			c.AllowNumericAmounts = true
+			c.DefaultRetailer = "Unknown"
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			newTestHandler(t, tc.configure)
+			encoded, err := buildOpenAPI()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc map[string]interface{}
+			if err := json.Unmarshal(encoded, &doc); err != nil {
+				t.Fatal(err)
+			}
+			receiptSchema := map[string]interface{}{"$ref": "#/components/schemas/Receipt"}
+
+			for _, sample := range samples {
+				var receipt map[string]interface{}
+				if err := json.Unmarshal([]byte(targetReceipt), &receipt); err != nil {
+					t.Fatal(err)
+				}
+				if sample.value == nil {
+					delete(receipt, sample.field)
+				} else {
+					receipt[sample.field] = sample.value
+				}
+				body, _ := json.Marshal(receipt)
+
+				// Decode the body afresh so the schema sees JSON types, not Go ones
+				var decoded interface{}
+				json.Unmarshal(body, &decoded)
+				spec, validator := schemaAccepts(t, doc, receiptSchema, decoded), validatorAccepts(string(body))
+				if spec != validator {
+					t.Errorf("%s=%v: schema accepts %v, validation accepts %v", sample.field, sample.value, spec, validator)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// recalculate.go
+// This file contains the administrative handlers that rescore stored receipts
+// under the current rule configuration.
+
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+)
+
+// recalculateResult reports how recalculation changed the points of one receipt.
+type recalculateResult struct {
+	ID        string `json:"id"`        // Identifier of the receipt
+	OldPoints int    `json:"oldPoints"` // Points stored before recalculation
+	NewPoints int    `json:"newPoints"` // Points under the current rule configuration
+}
+
+// recalculateAllResponse is the body returned by RecalculateAllReceipts.
+type recalculateAllResponse struct {
+	Receipts  int                 `json:"receipts"`  // Number of receipts recalculated
+	OldPoints int                 `json:"oldPoints"` // Sum of points across those receipts before recalculation
+	NewPoints int                 `json:"newPoints"` // Sum of points across those receipts afterwards
+	Changed   []recalculateResult `json:"changed"`   // Receipts whose points changed, in insertion order
+}
+
+// RecalculateReceipt handles the POST request to rescore one stored receipt. It runs
+// the current rules over the stored source receipt, replaces its points and
+// breakdown, and returns the old and new point totals.
+func (h *Handler) RecalculateReceipt(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	result, err := h.recalculate(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+			return
+		}
+		log.Printf("failed to recalculate receipt %s: %v", id, err)
+		writeError(w, r, http.StatusInternalServerError, "Failed to recalculate receipt")
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// RecalculateAllReceipts handles the POST request to rescore every stored receipt,
+// returning the point totals before and after along with each receipt whose points
+// changed. Receipts deleted while it runs are skipped.
+func (h *Handler) RecalculateAllReceipts(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	// Collect the receipts first, as the store may not be called back from Range
+	var stored []*models.ProcessedReceipt
+	err := h.store.RangeContext(r.Context(), func(receipt *models.ProcessedReceipt) bool {
+		stored = append(stored, receipt)
+		return true
+	})
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Sequence < stored[j].Sequence })
+
+	resp := recalculateAllResponse{Changed: []recalculateResult{}}
+	for _, receipt := range stored {
+		// Each update is atomic, so stopping early leaves the rest at their old points
+		if err := r.Context().Err(); err != nil {
+			writeContextError(w, r, err)
+			return
+		}
+		result, err := h.recalculate(receipt.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("failed to recalculate receipt %s: %v", receipt.ID, err)
+			writeError(w, r, http.StatusInternalServerError, "Failed to recalculate receipts")
+			return
+		}
+		resp.Receipts++
+		resp.OldPoints += result.OldPoints
+		resp.NewPoints += result.NewPoints
+		if result.NewPoints != result.OldPoints {
+			resp.Changed = append(resp.Changed, result)
+		}
+	}
+
+	writeJSON(w, r, http.StatusOK, resp)
+}
+
+// recalculate atomically replaces the points and breakdown of the receipt stored
+// under id with those the current rules award its source receipt, stamping its
+// modification time from the clock.
+func (h *Handler) recalculate(id string) (recalculateResult, error) {
+	result := recalculateResult{ID: id}
+	_, err := h.store.Update(id, func(stored *models.ProcessedReceipt) {
+		result.OldPoints = stored.Points
+		stored.Points, stored.Breakdown = rescore(stored)
+		stored.ModifiedAt = clock.Now()
+		result.NewPoints = stored.Points
+	})
+	return result, err
+}
+
+// rescore calculates the points of a stored receipt under the current rules. The
+// first-purchase-of-day bonus depends on the receipts stored when it was processed,
+// so it is kept, at the current weight, only if the receipt earned it originally.
+func rescore(stored *models.ProcessedReceipt) (int, []models.RuleResult) {
+	receipt := stored.Receipt
+	points, breakdown := calculatePoints(&receipt, cfg.Rules)
+	for _, result := range stored.Breakdown {
+		if result.Rule == firstPurchaseOfDayRule && cfg.Rules.FirstPurchaseOfDayBonus != 0 {
+			points += cfg.Rules.FirstPurchaseOfDayBonus
+			breakdown = append(breakdown, firstPurchaseOfDayResult())
+		}
+	}
+	return points, breakdown
+}
@@ -0,0 +1,188 @@
+// report.go
+// This file contains the per-user daily points report used for loyalty statements.
+
+package handlers
+
+import (
+	"encoding/csv"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// reportHeader names the columns of the CSV daily report
+var reportHeader = []string{"date", "points", "receipts"}
+
+// dailyPoints is one day of a user's points report.
+type dailyPoints struct {
+	Date     string `json:"date"`     // Purchase date in UTC, YYYY-MM-DD
+	Points   int    `json:"points"`   // Points awarded to the receipts purchased that day
+	Receipts int    `json:"receipts"` // Number of receipts purchased that day
+}
+
+// dailyReportResponse is the JSON body returned by GetUserReport.
+type dailyReportResponse struct {
+	User     string        `json:"user"`           // Subject the report was generated for
+	From     string        `json:"from,omitempty"` // First purchase date included, when bounded
+	To       string        `json:"to,omitempty"`   // Last purchase date included, when bounded
+	Points   int           `json:"points"`         // Points across every day of the report
+	Receipts int           `json:"receipts"`       // Receipts across every day of the report
+	Days     []dailyPoints `json:"days"`           // Days with at least one receipt, in date order
+}
+
+// receiptPredicate selects stored receipts while iterating the store.
+type receiptPredicate func(stored *models.ProcessedReceipt) bool
+
+// ownedBy selects the receipts submitted by subject.
+func ownedBy(subject string) receiptPredicate {
+	return func(stored *models.ProcessedReceipt) bool {
+		return stored.Owner == subject
+	}
+}
+
+// purchasedBetween selects the receipts purchased from the UTC date from through
+// the UTC date to, both inclusive; an empty bound leaves that side open. Dates share
+// the YYYY-MM-DD layout, so they compare lexically.
+func purchasedBetween(from, to string) receiptPredicate {
+	return func(stored *models.ProcessedReceipt) bool {
+		date := purchaseDateUTC(stored)
+		return (from == "" || date >= from) && (to == "" || date <= to)
+	}
+}
+
+// purchaseDateUTC returns the UTC date, YYYY-MM-DD, a stored receipt was purchased
+// on, so receipts from every region are grouped consistently. Receipts stored
+// before purchase times were kept in UTC fall back to their date as written.
+func purchaseDateUTC(stored *models.ProcessedReceipt) string {
+	if stored.PurchasedAt.IsZero() {
+		return stored.Receipt.PurchaseDate
+	}
+	return stored.PurchasedAt.UTC().Format("2006-01-02")
+}
+
+// matchesAll reports whether stored satisfies every predicate.
+func matchesAll(stored *models.ProcessedReceipt, predicates ...receiptPredicate) bool {
+	for _, matches := range predicates {
+		if !matches(stored) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetUserReport handles the GET request for the authenticated user's daily points
+// report. It totals the points and receipts of each UTC purchase date, optionally
+// bounded by the from and to dates (YYYY-MM-DD, inclusive), and responds with CSV
+// when the client prefers text/csv in its Accept header, or JSON otherwise.
+func (h *Handler) GetUserReport(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; the report covers only its subject
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse the optional purchase date range
+	from, ok := parseDateParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseDateParam(w, r, "to")
+	if !ok {
+		return
+	}
+	if from != "" && to != "" && to < from {
+		writeError(w, r, http.StatusBadRequest, "to must not be before from")
+		return
+	}
+
+	// Aggregate the user's receipts by purchase date
+	resp := dailyReportResponse{User: claims.Subject, From: from, To: to, Days: []dailyPoints{}}
+	byDate := make(map[string]*dailyPoints)
+	predicates := []receiptPredicate{ownedBy(claims.Subject), purchasedBetween(from, to)}
+	if err := h.store.RangeContext(r.Context(), func(stored *models.ProcessedReceipt) bool {
+		if !matchesAll(stored, predicates...) {
+			return true
+		}
+		date := purchaseDateUTC(stored)
+		day, ok := byDate[date]
+		if !ok {
+			day = &dailyPoints{Date: date}
+			byDate[day.Date] = day
+		}
+		day.Points += stored.Points
+		day.Receipts++
+		resp.Points += stored.Points
+		resp.Receipts++
+		return true
+	}); err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	for _, day := range byDate {
+		resp.Days = append(resp.Days, *day)
+	}
+	sort.Slice(resp.Days, func(i, j int) bool { return resp.Days[i].Date < resp.Days[j].Date })
+
+	// The representation depends on the Accept header
+	w.Header().Add("Vary", "Accept")
+	if !prefersCSV(r.Header.Get("Accept")) {
+		writeJSON(w, r, http.StatusOK, resp)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write(reportHeader)
+	for _, day := range resp.Days {
+		cw.Write([]string{day.Date, strconv.Itoa(day.Points), strconv.Itoa(day.Receipts)})
+	}
+	cw.Flush()
+}
+
+// parseDateParam parses the YYYY-MM-DD query parameter name, returning an empty
+// string when it is absent. On a malformed value it writes a 400 response and
+// returns false.
+func parseDateParam(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return "", true
+	}
+	if _, err := time.Parse("2006-01-02", v); err != nil {
+		writeError(w, r, http.StatusBadRequest, name+" must be a date in YYYY-MM-DD format")
+		return "", false
+	}
+	return v, true
+}
+
+// prefersCSV reports whether an Accept header ranks text/csv above JSON. Wildcard
+// ranges count towards JSON, the default representation, so only clients that
+// explicitly ask for CSV receive it.
+func prefersCSV(accept string) bool {
+	csvQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "text/csv":
+			csvQ = max(csvQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return csvQ > 0 && csvQ > jsonQ
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+)
+
+func TestUserReport(t *testing.T) {
+	h := newTestHandler(t, func(c *config.Config) { c.DefaultTimezone = time.UTC })
+	mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)                                // 2022-01-01, 28 points
+	mustProcess(t, h, "alice", withReceipt(t, "purchaseTime", "14:30"), http.StatusCreated)      // 2022-01-01, 38 points
+	mustProcess(t, h, "alice", withReceipt(t, "purchaseDate", "2022-01-02"), http.StatusCreated) // 2022-01-02, 22 points
+	mustProcess(t, h, "bob", withReceipt(t, "purchaseDate", "2022-01-03"), http.StatusCreated)
+
+	report := func(query, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/v1/users/me/report"+query, nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		authorize(t, r, "alice")
+		w := httptest.NewRecorder()
+		h.GetUserReport(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("report%s: status %d (%s)", query, w.Code, strings.TrimSpace(w.Body.String()))
+		}
+		return w
+	}
+
+	for _, tc := range []struct {
+		query string
+		want  dailyReportResponse
+	}{
+		{"", dailyReportResponse{User: "alice", Points: 88, Receipts: 3, Days: []dailyPoints{
+			{Date: "2022-01-01", Points: 66, Receipts: 2},
+			{Date: "2022-01-02", Points: 22, Receipts: 1},
+		}}},
+		{"?from=2022-01-02", dailyReportResponse{User: "alice", From: "2022-01-02", Points: 22, Receipts: 1, Days: []dailyPoints{
+			{Date: "2022-01-02", Points: 22, Receipts: 1},
+		}}},
+		{"?to=2021-12-31", dailyReportResponse{User: "alice", To: "2021-12-31", Days: []dailyPoints{}}},
+	} {
+		var got dailyReportResponse
+		if err := json.Unmarshal(report(tc.query, "").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("report%s = %+v, want %+v", tc.query, got, tc.want)
+		}
+	}
+
+	w := report("", "text/csv")
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want CSV", got)
+	}
+	if got, want := w.Body.String(), "date,points,receipts\n2022-01-01,66,2\n2022-01-02,22,1\n"; got != want {
+		t.Errorf("CSV report = %q, want %q", got, want)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept" {
+		t.Errorf("Vary = %q, want Accept", got)
+	}
+}
+
+func TestPrefersCSV(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                 false,
+		"text/csv":                         true,
+		"application/json":                 false,
+		"*/*":                              false,
+		"text/csv, application/json;q=0.5": true,
+		"text/csv;q=0.5, application/json": false,
+		"text/csv;q=0.5, */*":              false,
+		"text/csv;q=0":                     false,
+		"text/csv;q=abc, application/json": false,
+	} {
+		if got := prefersCSV(accept); got != want {
+			t.Errorf("prefersCSV(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// request.go
+// This file contains helpers for reading and checking request bodies before they
+// are decoded, and the receipt ID path parameter before it is looked up.
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// receiptID returns the {id} path parameter of r. IDs that are not UUIDs can never
+// match a stored receipt, so they are rejected with a 400 response, leaving 404 for
+// well-formed IDs that are unknown; on failure it returns false.
+func receiptID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid receipt ID format")
+		return "", false
+	}
+	return id, true
+}
+
+// readJSONBody checks that the request declares a JSON body, reads it, and rejects
+// it if its JSON nesting exceeds the configured maximum depth, so unwanted payloads
+// are refused before any decode work is done. The body size limit of the route is
+// applied by the BodyLimit middleware and reported here. On failure it writes a
+// 415, 413 or 400 response and returns false.
+func readJSONBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return nil, false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds the maximum of %d bytes", tooLarge.Limit))
+			return nil, false
+		}
+		writeError(w, r, http.StatusBadRequest, "Failed to read request body")
+		return nil, false
+	}
+	if err := checkJSONDepth(body, cfg.MaxJSONDepth); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// checkJSONDepth scans data and returns an error if objects and arrays are nested more
+// than maxDepth levels deep. Brackets inside strings are ignored. A maxDepth of zero
+// or less disables the check. Syntax errors are left for the JSON decoder to report.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("JSON nesting exceeds the maximum depth of %d", maxDepth)
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
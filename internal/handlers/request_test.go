@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckJSONDepth(t *testing.T) {
+	for _, tc := range []struct {
+		data     string
+		maxDepth int
+		ok       bool
+	}{
+		{`{"a":[{"b":1}]}`, 3, true},
+		{`{"a":[{"b":[1]}]}`, 3, false},
+		{`{"a":"[[[[{{{{"}`, 1, true},
+		{`{"a":"\"[["}`, 1, true},
+		{`[[[[[[[[[[]]]]]]]]]]`, 0, true},
+		{`[[[[[[[[[[]]]]]]]]]]`, 9, false},
+	} {
+		if err := checkJSONDepth([]byte(tc.data), tc.maxDepth); (err == nil) != tc.ok {
+			t.Errorf("checkJSONDepth(%s, %d) = %v, want ok %v", tc.data, tc.maxDepth, err, tc.ok)
+		}
+	}
+}
+
+func TestProcessReceiptRejectsDeeplyNestedPayloads(t *testing.T) {
+	h := newTestHandler(t, nil) // MaxJSONDepth defaults to 5
+
+	// targetReceipt is 3 levels deep; nesting one price 3 more levels exceeds the limit
+	body := strings.Replace(targetReceipt, `"6.49"`, `[[["6.49"]]]`, 1)
+	w := postReceipt(t, h, "alice", body)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "maximum depth of 5") {
+		t.Errorf("status %d (%s), want 400 naming the depth limit", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+func TestReadJSONBodyRejections(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		limit       int64 // Body size limit applied as the BodyLimit middleware would; zero for none
+		want        int
+		message     string
+	}{
+		{"accepted", "application/json", targetReceipt, 0, http.StatusCreated, ""},
+		{"charset parameter", "application/json; charset=utf-8", targetReceipt, 0, http.StatusCreated, ""},
+		{"wrong content type", "text/plain", targetReceipt, 0, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"missing content type", "", targetReceipt, 0, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"at the size limit", "application/json", targetReceipt, int64(len(targetReceipt)), http.StatusCreated, ""},
+		{"oversized", "application/json", targetReceipt, 64, http.StatusRequestEntityTooLarge, "Request body exceeds the maximum of 64 bytes"},
+		{"unknown field", "application/json", strings.Replace(targetReceipt, `"total"`, `"tip": "1.00", "total"`, 1), 0, http.StatusBadRequest, `Unknown field "tip"`},
+		{"unknown item field", "application/json", strings.Replace(targetReceipt, `"price": "6.49"`, `"price": "6.49", "sku": "1"`, 1), 0, http.StatusBadRequest, `Unknown field "sku"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, nil)
+			r := httptest.NewRequest(http.MethodPost, "/v1/receipts/process", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set("Content-Type", tc.contentType)
+			}
+			authorize(t, r, "alice")
+			w := httptest.NewRecorder()
+			if tc.limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, tc.limit)
+			}
+			h.ProcessReceipt(w, r)
+			if w.Code != tc.want {
+				t.Fatalf("status %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tc.want)
+			}
+			if got := strings.TrimSpace(w.Body.String()); tc.message != "" && got != tc.message {
+				t.Errorf("error = %q, want %q", got, tc.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// response.go
+// This file contains helpers for writing JSON and error responses, applying the
+// optional response envelope consistently across all handlers.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/middleware"
+)
+
+// envelope wraps a response body when the ENVELOPE option is enabled.
+type envelope struct {
+	Data  interface{} `json:"data,omitempty"`  // Successful response body
+	Error string      `json:"error,omitempty"` // Error message for failed requests
+	Meta  meta        `json:"meta"`            // Request metadata
+}
+
+// meta carries per-request metadata in enveloped responses.
+type meta struct {
+	RequestID  string  `json:"requestId,omitempty"` // ID assigned by the RequestID middleware
+	DurationMs float64 `json:"durationMs"`          // Time spent handling the request so far
+}
+
+// requestError is an error that maps to a specific HTTP status code.
+type requestError struct {
+	status  int    // HTTP status to respond with
+	message string // Message sent to the client
+}
+
+// Error returns the client-facing message.
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// errorStatus returns the HTTP status for err, defaulting to 500 for errors that
+// do not carry one.
+func errorStatus(err error) int {
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		return reqErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// statusClientClosedRequest is the non-standard status, borrowed from nginx, recorded
+// for requests abandoned by the client. The client never sees the response, but
+// logs and metrics then tell disconnects apart from failures.
+const statusClientClosedRequest = 499
+
+// contextError returns the requestError for a request context that ended before the
+// handler finished: 503 once the REQUEST_TIMEOUT deadline passes, or 499 when the
+// client went away. It returns nil for any other error.
+func contextError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &requestError{status: http.StatusServiceUnavailable, message: "Request timed out"}
+	case errors.Is(err, context.Canceled):
+		return &requestError{status: statusClientClosedRequest, message: "Client closed request"}
+	}
+	return nil
+}
+
+// writeContextError responds to a request whose store access failed with err,
+// using the status from contextError, or 500 for errors that are not context errors.
+func writeContextError(w http.ResponseWriter, r *http.Request, err error) {
+	if ctxErr := contextError(err); ctxErr != nil {
+		writeError(w, r, errorStatus(ctxErr), ctxErr.Error())
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, "Internal server error")
+}
+
+// writeJSON encodes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if cfg.Envelope {
+		v = envelope{Data: v, Meta: requestMeta(r)}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with an error message. Without the envelope this is a plain
+// text body, matching http.Error; with it the message is nested under "error".
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if !cfg.Envelope {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope{Error: message, Meta: requestMeta(r)})
+}
+
+// requestMeta builds the envelope metadata from the request context.
+func requestMeta(r *http.Request) meta {
+	m := meta{RequestID: middleware.GetRequestID(r.Context())}
+	if start := middleware.GetStartTime(r.Context()); !start.IsZero() {
+		m.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}
+	return m
+}
+
+// notModified sets the Last-Modified header from modifiedAt and, when the request's
+// If-Modified-Since is at or after that time, writes a 304 response and returns true.
+// HTTP dates have one-second resolution, so modifiedAt is truncated before comparing.
+func notModified(w http.ResponseWriter, r *http.Request, modifiedAt time.Time) bool {
+	if modifiedAt.IsZero() {
+		return false
+	}
+	modifiedAt = setLastModified(w, modifiedAt)
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modifiedAt.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// notModifiedETag sets the ETag header to etag, along with Last-Modified, and
+// writes a 304 response and returns true when the request's If-None-Match lists
+// etag or "*". If-None-Match takes precedence over If-Modified-Since, which is only
+// consulted when the request carries no If-None-Match.
+func notModifiedETag(w http.ResponseWriter, r *http.Request, etag string, modifiedAt time.Time) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return notModified(w, r, modifiedAt)
+	}
+	if !modifiedAt.IsZero() {
+		setLastModified(w, modifiedAt)
+	}
+
+	// If-None-Match uses the weak comparison, so a W/ prefix is ignored
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// setLastModified sets the Last-Modified header from modifiedAt, returning the time
+// truncated to the one-second resolution of HTTP dates.
+func setLastModified(w http.ResponseWriter, modifiedAt time.Time) time.Time {
+	modifiedAt = modifiedAt.Truncate(time.Second)
+	w.Header().Set("Last-Modified", modifiedAt.UTC().Format(http.TimeFormat))
+	return modifiedAt
+}
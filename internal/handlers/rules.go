@@ -0,0 +1,605 @@
+// rules.go
+// This file defines the point calculation rules applied to processed receipts.
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// rule is a single named point calculation rule. Receipt-level rules set points;
+// item-level rules set itemPoints, which is evaluated for every item so that the
+// points can be attributed to the items that earned them. Rules whose explanation
+// depends on configured weights set describe instead of description.
+type rule struct {
+	name        string                                               // Identifier reported in the points breakdown
+	description string                                               // Human-readable explanation of the rule
+	describe    func(rules config.RuleConfig) string                 // Explanation of the rule under the configured weights
+	points      func(r *models.Receipt, rules config.RuleConfig) int // Points the rule awards to a receipt
+	itemPoints  func(item models.Item, rules config.RuleConfig) int  // Points the rule awards to a single item
+}
+
+// pointRules lists every rule in evaluation order. Optional rules award zero points
+// unless they are enabled in the rule configuration.
+var pointRules = []rule{
+	// Rule 1: One point (by default) per alphanumeric character in retailer name
+	{name: "retailerAlphanumeric", describe: func(rules config.RuleConfig) string {
+		if rules.RetailerCharPoints == 1 {
+			return "One point for every alphanumeric character in the retailer name"
+		}
+		return fmt.Sprintf("%d points for every alphanumeric character in the retailer name", rules.RetailerCharPoints)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		return countAlphanumeric(r.Retailer) * rules.RetailerCharPoints
+	}},
+
+	// Rule 2: 50 points (by default) if the total is a round dollar amount
+	{name: "roundDollarTotal", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points if the total is a round dollar amount with no cents", rules.RoundDollarBonus)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if isTotalRoundDollar(r.Total) {
+			return rules.RoundDollarBonus
+		}
+		return 0
+	}},
+
+	// Rule 3: 25 points (by default) if the total is a multiple of 0.25
+	{name: "quarterMultipleTotal", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points if the total is a multiple of 0.25", rules.QuarterMultipleBonus)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if isTotalMultipleOf25Cents(r.Total) {
+			return rules.QuarterMultipleBonus
+		}
+		return 0
+	}},
+
+	// Rule 4: 5 points (by default) for every two items
+	{name: "itemPairs", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points for every two items on the receipt", rules.ItemPairPoints)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		return (len(r.Items) / 2) * rules.ItemPairPoints
+	}},
+
+	// Rule 5: Extra points if item description length is multiple of 3. Discount
+	// lines have no price to award points for, so they neither earn nor cost points.
+	// Each item is rounded up on its own, as the rule is specified ("multiply the
+	// price by 0.2 and round up to the nearest integer" per item): two qualifying
+	// items at $0.01 earn 1 point each, not one point between them. This also keeps
+	// the per-item points reported by ?items=true summing to the rule's total.
+	{name: "itemDescriptionLength", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d%% of the item price, rounded up, for each item whose trimmed description length is a multiple of 3", rules.DescriptionPricePercent)
+	}, itemPoints: func(item models.Item, rules config.RuleConfig) int {
+		if rules.DescriptionPricePercent > 0 && utf8.RuneCountInString(strings.TrimSpace(item.ShortDescription))%3 == 0 {
+			price, err := utils.ParseCents(item.Price)
+			if err != nil || price <= 0 {
+				return 0
+			}
+			// N% of the price in dollars is cents*N/10000, rounded up (cents/500 for the default 20%)
+			return int(ceilDiv(price*int64(rules.DescriptionPricePercent), 10000))
+		}
+		return 0
+	}},
+
+	// Rule 6: 6 points (by default) if purchase day is odd
+	{name: "oddPurchaseDay", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points if the day in the purchase date is odd", rules.OddDayBonus)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if isPurchaseDateOdd(r.PurchaseDate) {
+			return rules.OddDayBonus
+		}
+		return 0
+	}},
+
+	// Rule 7: 10 points (by default) if purchase time is between 2:00pm and 4:00pm on
+	// the receipt's own clock, whatever timezone it declares
+	{name: "afternoonPurchase", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points if the time of purchase is after 2:00pm and before 4:00pm", rules.AfternoonBonus)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if isPurchaseTimeBetween2And4PM(r.PurchaseTime) {
+			return rules.AfternoonBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: points scaled by the digit sum of the total in cents
+	{name: "totalDigitSum", description: "Points scaled by the digit sum of the total in cents", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.TotalDigitSumMultiplier == 0 {
+			return 0
+		}
+		cents, err := utils.ParseCents(r.Total)
+		if err != nil {
+			return 0
+		}
+		return digitSum(cents) * rules.TotalDigitSumMultiplier
+	}},
+
+	// Optional rule: bonus points if the item count is a prime number
+	{name: "primeItemCount", description: "Bonus points if the number of items is a prime number", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if isPrime(len(r.Items)) {
+			return rules.PrimeItemCountBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: points for each distinct item price on the receipt
+	{name: "distinctPrices", description: "Points for each distinct item price on the receipt", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.DistinctPricePoints == 0 {
+			return 0
+		}
+		return countDistinctPrices(r.Items) * rules.DistinctPricePoints
+	}},
+
+	// Optional rule: points for each item whose price has an even number of cents
+	{name: "evenCentsItems", description: "Points for each item whose price has an even number of cents", itemPoints: func(item models.Item, rules config.RuleConfig) int {
+		if rules.EvenCentsItemPoints == 0 {
+			return 0
+		}
+		// The cents part of a price is even exactly when the whole price in cents is
+		price, err := utils.ParseCents(item.Price)
+		if err != nil || price%2 != 0 {
+			return 0
+		}
+		return rules.EvenCentsItemPoints
+	}},
+
+	// Optional rule: points for each item priced at a round dollar amount, such as 5.00
+	{name: "roundDollarItems", description: "Points for each item whose price is a round dollar amount with no cents", itemPoints: func(item models.Item, rules config.RuleConfig) int {
+		if rules.RoundDollarItemPoints == 0 {
+			return 0
+		}
+		// Free items and discount lines are not rewarded
+		price, err := utils.ParseCents(item.Price)
+		if err != nil || price <= 0 || price%100 != 0 {
+			return 0
+		}
+		return rules.RoundDollarItemPoints
+	}},
+
+	// Optional rule: points for each item whose description contains a digit, such as a SKU
+	{name: "digitDescriptions", description: "Points for each item whose description contains a digit", itemPoints: func(item models.Item, rules config.RuleConfig) int {
+		if rules.DigitDescriptionPoints == 0 || !containsDigit(item.ShortDescription) {
+			return 0
+		}
+		return rules.DigitDescriptionPoints
+	}},
+
+	// Optional rule: points for every N characters of item description on the receipt
+	{name: "descriptionLength", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points for every %d characters across all trimmed item descriptions", rules.DescriptionLengthPoints, rules.DescriptionLengthChars)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.DescriptionLengthPoints == 0 || rules.DescriptionLengthChars <= 0 {
+			return 0
+		}
+		return totalDescriptionLength(r.Items) / rules.DescriptionLengthChars * rules.DescriptionLengthPoints
+	}},
+
+	// Optional rule: points for each vowel in the retailer name
+	{name: "retailerVowels", description: "Points for each vowel in the retailer name", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		return countVowels(r.Retailer) * rules.RetailerVowelPoints
+	}},
+
+	// Optional rule: bonus points if the total is a multiple of a configured amount
+	{name: "totalModulo", description: "Bonus points if the total is an exact multiple of the configured amount", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.TotalModuloBonus != 0 && isTotalMultipleOf(r.Total, rules.TotalModuloCents) {
+			return rules.TotalModuloBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: bonus points if every item has the same price
+	{name: "uniformBasket", description: "Bonus points if every item has the same price and there are enough items", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.UniformBasketBonus != 0 && len(r.Items) >= rules.UniformBasketMinItems && isUniformlyPriced(r.Items) {
+			return rules.UniformBasketBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: bonus points if the total contains a run of identical digits, such as 11.11
+	{name: "luckyTotal", description: "Bonus points if the digits of the total contain a run of identical digits", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.LuckyTotalBonus != 0 && rules.LuckyTotalRunLength > 0 && longestDigitRun(r.Total) >= rules.LuckyTotalRunLength {
+			return rules.LuckyTotalBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: bonus points if the retailer name checksum hits the configured target
+	{name: "retailerChecksum", description: "Bonus points if the checksum of the retailer name equals the configured target", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.RetailerChecksumBonus != 0 && rules.RetailerChecksumModulus > 0 && retailerChecksum(r.Retailer, rules.RetailerChecksumModulus) == rules.RetailerChecksumTarget {
+			return rules.RetailerChecksumBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: campaign bonus that shrinks linearly from its full value at the
+	// campaign start to zero at the campaign end, based on when the receipt is processed
+	{name: "campaignBonus", description: "Campaign bonus points that decrease over the life of the campaign", points: func(_ *models.Receipt, rules config.RuleConfig) int {
+		return campaignBonus(clock.Now(), rules)
+	}},
+
+	// Optional rule: bonus points if the formatted purchase date is a palindrome, such as 02-02-2020
+	{name: "palindromeDate", describe: func(rules config.RuleConfig) string {
+		return fmt.Sprintf("%d points if the digits of the purchase date formatted as %q read the same backwards", rules.PalindromeDateBonus, rules.PalindromeDateFormat)
+	}, points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.PalindromeDateBonus != 0 && isPalindromeDate(r.PurchaseDate, r.PurchaseTime, rules.PalindromeDateFormat) {
+			return rules.PalindromeDateBonus
+		}
+		return 0
+	}},
+
+	// Optional rule: bonus points if the purchase date is a configured holiday
+	{name: "holidayPurchase", description: "Bonus points for purchases made on a holiday", points: func(r *models.Receipt, rules config.RuleConfig) int {
+		if rules.HolidayBonus != 0 && isHoliday(r.PurchaseDate, rules.Holidays) {
+			return rules.HolidayBonus
+		}
+		return 0
+	}},
+}
+
+// calculatePoints calculates the points for the receipt based on predefined rules
+// and any optional rules enabled in the rule configuration. Along with the total it
+// returns a breakdown listing each rule that awarded points; for item-level rules
+// the breakdown also records the points earned by each item index.
+func calculatePoints(r *models.Receipt, rules config.RuleConfig) (int, []models.RuleResult) {
+	total := 0
+	var breakdown []models.RuleResult
+
+	for _, rl := range pointRules {
+		result, ok := evaluateRule(rl, r, rules)
+		if !ok || result.Points == 0 {
+			continue
+		}
+		total += result.Points
+		breakdown = append(breakdown, result)
+	}
+
+	// Apply the purchase month multiplier to the final total, recording the
+	// difference as its own breakdown entry so the breakdown still sums to the total.
+	// The first-purchase-of-day bonus is added by the caller afterwards, so it is
+	// never multiplied.
+	if multiplier, ok := monthMultiplier(r.PurchaseDate, rules); ok {
+		adjusted := int(math.Round(float64(total) * multiplier))
+		if delta := adjusted - total; delta != 0 {
+			total = adjusted
+			breakdown = append(breakdown, models.RuleResult{
+				Rule:        "purchaseMonthMultiplier",
+				Points:      delta,
+				Description: fmt.Sprintf("Total multiplied by %g for purchases in %s", multiplier, monthName(r.PurchaseDate)),
+			})
+		}
+	}
+
+	return total, breakdown
+}
+
+// evaluateRule applies a single rule to the receipt. In lenient mode a panicking rule
+// is logged and skipped, returning false, so the remaining rules still score the
+// receipt; in strict mode the panic propagates and fails the request.
+func evaluateRule(rl rule, r *models.Receipt, rules config.RuleConfig) (result models.RuleResult, ok bool) {
+	if rules.Lenient {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("scoring rule %s panicked and was skipped: %v", rl.name, p)
+				result, ok = models.RuleResult{}, false
+			}
+		}()
+	}
+
+	result = models.RuleResult{Rule: rl.name, Description: rl.description}
+	if rl.describe != nil {
+		result.Description = rl.describe(rules)
+	}
+	if rl.itemPoints != nil {
+		for idx, item := range r.Items {
+			if points := rl.itemPoints(item, rules); points != 0 {
+				if result.Items == nil {
+					result.Items = make(map[int]int)
+				}
+				result.Items[idx] = points
+				result.Points += points
+			}
+		}
+	} else {
+		result.Points = rl.points(r, rules)
+	}
+	return result, true
+}
+
+// itemPoints sums the item-level contributions in a breakdown, keyed by item index.
+func itemPoints(breakdown []models.RuleResult) map[int]int {
+	items := make(map[int]int)
+	for _, result := range breakdown {
+		for idx, points := range result.Items {
+			items[idx] += points
+		}
+	}
+	return items
+}
+
+// Helper functions for calculating points
+
+// countAlphanumeric counts the Unicode letters and decimal digits in a string, so
+// "Café München 123" has 14. Combining marks are not counted: an accent written as
+// a separate mark adds nothing to its base letter, so precomposed and decomposed
+// spellings of a name score the same.
+func countAlphanumeric(s string) int {
+	count := 0
+	for _, char := range s {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			count++
+		}
+	}
+	return count
+}
+
+// containsDigit reports whether s contains a Unicode decimal digit.
+func containsDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+// longestDigitRun returns the length of the longest run of identical digits in the
+// normalized total, ignoring the sign and decimal point, so "11.11" has a run of 4
+// and "12.34" a run of 1. Totals that cannot be parsed have no run.
+func longestDigitRun(total string) int {
+	cents, err := utils.ParseCents(total)
+	if err != nil {
+		return 0
+	}
+	digits := strings.NewReplacer("-", "", ".", "").Replace(utils.FormatCents(cents))
+
+	longest, run := 0, 0
+	for i := range digits {
+		if i > 0 && digits[i] == digits[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+// retailerChecksum returns the sum of the Unicode code points of the trimmed retailer
+// name modulo modulus, which must be positive. Surrounding whitespace is ignored so
+// that it cannot change the outcome.
+func retailerChecksum(retailer string, modulus int) int {
+	sum := 0
+	for _, char := range strings.TrimSpace(retailer) {
+		sum = (sum + int(char)) % modulus
+	}
+	return sum
+}
+
+// totalDescriptionLength returns the number of characters (runes, not bytes) in the
+// trimmed descriptions of all items.
+func totalDescriptionLength(items []models.Item) int {
+	total := 0
+	for _, item := range items {
+		total += utf8.RuneCountInString(strings.TrimSpace(item.ShortDescription))
+	}
+	return total
+}
+
+// vowels lists the lowercase vowels recognized by countVowels, including their
+// common accented Latin forms so that names such as "Café" count every vowel.
+const vowels = "aeiouàáâãäåāăąèéêëēĕėęěìíîïĩīĭįòóôõöøōŏőùúûüũūŭůűų"
+
+// countVowels counts the vowels in a string, ignoring case.
+func countVowels(s string) int {
+	count := 0
+	for _, char := range s {
+		if strings.ContainsRune(vowels, unicode.ToLower(char)) {
+			count++
+		}
+	}
+	return count
+}
+
+// isTotalMultipleOf25Cents checks if the total is a multiple of 0.25.
+// It compares exact cents, since float64 cannot represent values like 4.35 exactly.
+func isTotalMultipleOf25Cents(total string) bool {
+	cents, err := utils.ParseCents(total)
+	if err != nil {
+		return false
+	}
+	return cents%25 == 0
+}
+
+// isTotalMultipleOf checks if the total is an exact multiple of divisor cents.
+// A non-positive divisor never matches.
+func isTotalMultipleOf(total string, divisor int64) bool {
+	if divisor <= 0 {
+		return false
+	}
+	cents, err := utils.ParseCents(total)
+	if err != nil {
+		return false
+	}
+	return cents%divisor == 0
+}
+
+// isTotalRoundDollar checks if the total is a whole number of dollars.
+func isTotalRoundDollar(total string) bool {
+	cents, err := utils.ParseCents(total)
+	if err != nil {
+		return false
+	}
+	return cents%100 == 0
+}
+
+// ceilDiv divides a by b, rounding up, for non-negative a and positive b.
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+// digitSum returns the sum of the decimal digits of n.
+func digitSum(n int64) int {
+	if n < 0 {
+		n = -n
+	}
+	sum := 0
+	for ; n > 0; n /= 10 {
+		sum += int(n % 10)
+	}
+	return sum
+}
+
+// countDistinctPrices counts the distinct item prices, compared as exact cents so that
+// equivalent amounts are never split by formatting. Unparseable prices are ignored.
+func countDistinctPrices(items []models.Item) int {
+	seen := make(map[int64]struct{}, len(items))
+	for _, item := range items {
+		if cents, err := utils.ParseCents(item.Price); err == nil {
+			seen[cents] = struct{}{}
+		}
+	}
+	return len(seen)
+}
+
+// isUniformlyPriced checks if every item has the same price, compared as exact cents.
+// Receipts with no items or an unparseable price are not uniform.
+func isUniformlyPriced(items []models.Item) bool {
+	if len(items) == 0 {
+		return false
+	}
+	first, err := utils.ParseCents(items[0].Price)
+	if err != nil {
+		return false
+	}
+	for _, item := range items[1:] {
+		if cents, err := utils.ParseCents(item.Price); err != nil || cents != first {
+			return false
+		}
+	}
+	return true
+}
+
+// campaignBonus returns the campaign bonus at now: the full bonus at the campaign
+// start, decreasing linearly to zero at the campaign end, and zero outside the
+// campaign or when no campaign is configured.
+func campaignBonus(now time.Time, rules config.RuleConfig) int {
+	if rules.CampaignBonus == 0 || !rules.CampaignEnd.After(rules.CampaignStart) {
+		return 0
+	}
+	if now.Before(rules.CampaignStart) || !now.Before(rules.CampaignEnd) {
+		return 0
+	}
+	remaining := float64(rules.CampaignEnd.Sub(now)) / float64(rules.CampaignEnd.Sub(rules.CampaignStart))
+	return int(math.Round(float64(rules.CampaignBonus) * remaining))
+}
+
+// isPrime checks if n is a prime number.
+func isPrime(n int) bool {
+	if n < 2 {
+		return false
+	}
+	for d := 2; d*d <= n; d++ {
+		if n%d == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// monthMultiplier returns the configured multiplier for the month of date, a
+// purchase date as written on the receipt.
+func monthMultiplier(date string, rules config.RuleConfig) (float64, bool) {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return 0, false
+	}
+	multiplier, ok := rules.MonthMultipliers[t.Month()]
+	return multiplier, ok
+}
+
+// monthName returns the name of the month of date, a purchase date in YYYY-MM-DD format.
+func monthName(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return t.Month().String()
+}
+
+// isHoliday checks if the purchase date matches a holiday. Holidays written as MM-DD
+// recur every year, while YYYY-MM-DD holidays match only that specific date.
+func isHoliday(date string, holidays []string) bool {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	monthDay := t.Format("01-02")
+	for _, holiday := range holidays {
+		if holiday == date || holiday == monthDay {
+			return true
+		}
+	}
+	return false
+}
+
+// isPalindromeDate reports whether the digits of the purchase date and time, as
+// written on the receipt and formatted with layout, read the same forwards and
+// backwards. Separators in the layout are ignored, so with "01-02-2006" the date
+// 2020-02-02 forms "02022020". The time is only read when the layout shows it and
+// the receipt has one, so a date-only layout also matches receipts without a time.
+// Layouts that produce no digits never match.
+func isPalindromeDate(date, purchaseTime, layout string) bool {
+	var t time.Time
+	var err error
+	if purchaseTime == "" || !layoutShowsTime(layout) {
+		t, err = time.Parse("2006-01-02", date)
+	} else {
+		t, err = time.Parse("2006-01-02 15:04", date+" "+purchaseTime)
+	}
+	if err != nil {
+		return false
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, t.Format(layout))
+	if digits == "" {
+		return false
+	}
+	for i, j := 0, len(digits)-1; i < j; i, j = i+1, j-1 {
+		if digits[i] != digits[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutShowsTime reports whether times formatted with layout depend on the time of
+// day, by formatting the same date at two different times.
+func layoutShowsTime(layout string) bool {
+	midnight := time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)
+	return midnight.Format(layout) != midnight.Add(13*time.Hour+4*time.Minute).Format(layout)
+}
+
+// isPurchaseDateOdd checks if the purchase date day is odd.
+func isPurchaseDateOdd(date string) bool {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	return t.Day()%2 != 0
+}
+
+// isPurchaseTimeBetween2And4PM checks if purchase time is between 2:00pm and 4:00pm.
+func isPurchaseTimeBetween2And4PM(timeStr string) bool {
+	t, err := time.Parse("15:04", timeStr)
+	if err != nil {
+		return false
+	}
+	return t.Hour() >= 14 && t.Hour() < 16
+}
@@ -0,0 +1,628 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+func TestIsPalindromeDate(t *testing.T) {
+	for _, tc := range []struct {
+		date, purchaseTime, layout string
+		want                       bool
+	}{
+		{"2020-02-02", "13:01", "01-02-2006", true}, // 02022020
+		{"2020-02-02", "", "01-02-2006", true},
+		{"2021-12-02", "", "2006-01-02", true}, // 20211202
+		{"2022-01-01", "13:01", "01-02-2006", false},
+		{"2022-01-01", "", "01-02-2006", false},
+		{"2020-02-02", "12:21", "15:04", true},
+		{"2020-02-02", "12:22", "15:04", false},
+		{"2021-12-02", "12:02", "2006-01-02 15:04", false}, // 202112021202
+		{"2022-13-01", "13:01", "01-02-2006", false},
+		{"2020-02-02", "13:01", "Jan Mon", false}, // No digits
+	} {
+		if got := isPalindromeDate(tc.date, tc.purchaseTime, tc.layout); got != tc.want {
+			t.Errorf("isPalindromeDate(%q, %q, %q) = %v, want %v", tc.date, tc.purchaseTime, tc.layout, got, tc.want)
+		}
+	}
+}
+
+func TestItemDescriptionLengthRoundsEachItemUp(t *testing.T) {
+	rules := config.Default().Rules
+	items := func(pairs ...string) []models.Item {
+		var items []models.Item
+		for i := 0; i < len(pairs); i += 2 {
+			items = append(items, models.Item{ShortDescription: pairs[i], Price: pairs[i+1]})
+		}
+		return items
+	}
+
+	for _, tc := range []struct {
+		name  string
+		items []models.Item
+		want  map[int]int // Points per item index; the rule total is their sum
+	}{
+		{"one cent", items("abc", "0.01"), map[int]int{0: 1}},
+		{"five cents", items("abc", "0.05"), map[int]int{0: 1}},
+		{"fractional points", items("abc", "12.25"), map[int]int{0: 3}},
+		{"round dollars", items("abc", "12.00"), map[int]int{0: 3}},
+		{"exact points", items("abc", "5.00"), map[int]int{0: 1}},
+		{"one cent remainder", items("abc", "5.01"), map[int]int{0: 2}},
+		{"each item rounded up", items("abc", "0.01", "xyz", "0.01"), map[int]int{0: 1, 1: 1}},
+		{"only qualifying items", items("abc", "12.25", "ab", "3.00", "  abcdef ", "0.05"), map[int]int{0: 3, 2: 1}},
+		{"discounts earn nothing", items("abc", "-1.00", "abc", "0.00"), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			receipt := &models.Receipt{Retailer: "Target", PurchaseDate: "2022-01-02", PurchaseTime: "10:00", Total: "1.00", Items: tc.items}
+			_, breakdown := calculatePoints(receipt, rules)
+
+			wantTotal := 0
+			for _, points := range tc.want {
+				wantTotal += points
+			}
+			var got models.RuleResult
+			for _, result := range breakdown {
+				if result.Rule == "itemDescriptionLength" {
+					got = result
+				}
+			}
+			if got.Points != wantTotal {
+				t.Errorf("rule total = %d, want %d", got.Points, wantTotal)
+			}
+			if len(got.Items) != len(tc.want) {
+				t.Errorf("per-item points = %v, want %v", got.Items, tc.want)
+			}
+			for idx, points := range tc.want {
+				if got.Items[idx] != points {
+					t.Errorf("item %d: %d points, want %d (all items: %v)", idx, got.Items[idx], points, got.Items)
+				}
+			}
+		})
+	}
+}
+
+func TestUnicodeRetailerNames(t *testing.T) {
+	for _, tc := range []struct {
+		retailer string
+		want     int
+	}{
+		{"Target", 6},
+		{"M&M Corner Market", 14},
+		{"Café München 123", 14},
+		{"Café", 4},       // Precomposed é
+		{"Cafe\u0301", 4}, // e followed by a combining acute accent
+		{"Ñandú", 5},
+		{"東京ストア", 5},
+		{"Магазин 24", 9},
+		{"متجر", 4},
+	} {
+		if got := countAlphanumeric(tc.retailer); got != tc.want {
+			t.Errorf("countAlphanumeric(%q) = %d, want %d", tc.retailer, got, tc.want)
+		}
+		if !retailerRegex.MatchString(tc.retailer) {
+			t.Errorf("retailer %q is rejected by validation", tc.retailer)
+		}
+	}
+}
+
+func TestItemDescriptionLengthCountsCharacters(t *testing.T) {
+	rules := config.Default().Rules
+	var descriptionRule rule
+	for _, rl := range pointRules {
+		if rl.name == "itemDescriptionLength" {
+			descriptionRule = rl
+		}
+	}
+
+	// "Thé" is three characters but four bytes, "Café" four characters but five bytes
+	for description, want := range map[string]int{
+		"Thé":    1,
+		"Café":   0,
+		"茶葉":     0,
+		"緑茶葉":    1,
+		"abcdef": 1,
+	} {
+		item := models.Item{ShortDescription: description, Price: "1.00"}
+		if got := descriptionRule.itemPoints(item, rules); got != want {
+			t.Errorf("%q: %d points, want %d", description, got, want)
+		}
+	}
+}
+
+func TestMonthMultiplier(t *testing.T) {
+	rules := config.Default().Rules
+	rules.MonthMultipliers = map[time.Month]float64{time.December: 1.5}
+
+	// The receipt scores 22 points before the multiplier on even-numbered days
+	for _, tc := range []struct {
+		name             string
+		date, time, zone string
+		want             int
+	}{
+		{"December", "2022-12-02", "13:01", "", 33},
+		{"June", "2022-06-02", "13:01", "", 22},
+		{"late November in New York stays November", "2022-11-30", "23:30", "America/New_York", 22},
+		{"early December in Tokyo stays December", "2022-12-01", "08:00", "Asia/Tokyo", 42}, // Odd day: 28 points before the multiplier
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			receipt := &models.Receipt{
+				Retailer:     "Target",
+				PurchaseDate: tc.date,
+				PurchaseTime: tc.time,
+				Timezone:     tc.zone,
+				Total:        "35.35",
+				Items: []models.Item{
+					{ShortDescription: "Mountain Dew 12PK", Price: "6.49"},
+					{ShortDescription: "Emils Cheese Pizza", Price: "12.25"},
+					{ShortDescription: "Knorr Creamy Chicken", Price: "1.26"},
+					{ShortDescription: "Doritos Nacho Cheese", Price: "3.35"},
+					{ShortDescription: "   Klarbrunn 12-PK 12 FL OZ  ", Price: "12.00"},
+				},
+			}
+			if got, _ := calculatePoints(receipt, rules); got != tc.want {
+				t.Errorf("points = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPanickingRuleInLenientMode(t *testing.T) {
+	// A rule that panics on every receipt, evaluated between the built-in rules
+	previous := pointRules
+	t.Cleanup(func() { pointRules = previous })
+	faulty := rule{name: "faulty", points: func(*models.Receipt, config.RuleConfig) int { panic("misconfigured rule") }}
+	pointRules = append(append(append([]rule(nil), previous[:2]...), faulty), previous[2:]...)
+
+	h := newTestHandler(t, func(c *config.Config) { c.Rules.Lenient = true })
+	id := mustProcess(t, h, "alice", targetReceipt, http.StatusCreated)
+	if got := pointsOf(t, h, "alice", id); got != 28 {
+		t.Errorf("points = %d, want the 28 the other rules award", got)
+	}
+	receipt, _ := h.store.Get(id)
+	for _, result := range receipt.Breakdown {
+		if result.Rule == "faulty" {
+			t.Errorf("breakdown includes the panicking rule: %+v", result)
+		}
+	}
+
+	// Strict mode lets the panic fail the request
+	defer func() {
+		if recover() == nil {
+			t.Error("strict mode scored the receipt despite the panicking rule")
+		}
+	}()
+	var r models.Receipt
+	if err := decodeReceipt(strings.NewReader(targetReceipt), &r); err != nil {
+		t.Fatal(err)
+	}
+	calculatePoints(&r, config.Default().Rules)
+}
+
+// ruleNamed returns the point rule with the given name.
+func ruleNamed(t *testing.T, name string) rule {
+	t.Helper()
+	for _, rl := range pointRules {
+		if rl.name == name {
+			return rl
+		}
+	}
+	t.Fatalf("no rule named %s", name)
+	return rule{}
+}
+
+func TestTotalDigitSumRule(t *testing.T) {
+	digitSumRule := ruleNamed(t, "totalDigitSum")
+	rules := config.Default().Rules
+	if got := digitSumRule.points(&models.Receipt{Total: "12.34"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.TotalDigitSumMultiplier = 2
+	for total, want := range map[string]int{
+		"12.34":  20, // 1+2+3+4 = 10
+		"12.30":  12,
+		"0.00":   0,
+		"100.00": 2,
+		"9.99":   54,
+		"-12.34": 20,
+		"abc":    0,
+	} {
+		if got := digitSumRule.points(&models.Receipt{Total: total}, rules); got != want {
+			t.Errorf("total %s: %d points, want %d", total, got, want)
+		}
+	}
+}
+
+func TestPrimeItemCountRule(t *testing.T) {
+	primeRule := ruleNamed(t, "primeItemCount")
+	rules := config.Default().Rules
+	if got := primeRule.points(&models.Receipt{Items: make([]models.Item, 3)}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.PrimeItemCountBonus = 5
+	for count, want := range map[int]int{0: 0, 1: 0, 2: 5, 3: 5, 4: 0, 7: 5, 9: 0} {
+		if got := primeRule.points(&models.Receipt{Items: make([]models.Item, count)}, rules); got != want {
+			t.Errorf("%d items: %d points, want %d", count, got, want)
+		}
+	}
+}
+
+func TestAfternoonBonusUsesTheReceiptsOwnClock(t *testing.T) {
+	afternoonRule := ruleNamed(t, "afternoonPurchase")
+	rules := config.Default().Rules
+	for _, tc := range []struct {
+		time, zone string
+		want       int
+	}{
+		{"14:30", "", 10},
+		{"14:30", "America/New_York", 10}, // 19:30 in UTC
+		{"14:30", "Asia/Tokyo", 10},       // 05:30 in UTC
+		{"09:30", "America/New_York", 0},  // 14:30 in UTC
+		{"13:59", "Europe/Berlin", 0},
+		{"16:00", "Europe/Berlin", 0},
+	} {
+		receipt := &models.Receipt{PurchaseDate: "2022-01-01", PurchaseTime: tc.time, Timezone: tc.zone}
+		if got := afternoonRule.points(receipt, rules); got != tc.want {
+			t.Errorf("%s in %q: %d points, want %d", tc.time, tc.zone, got, tc.want)
+		}
+	}
+}
+
+func TestNegativeDiscountItem(t *testing.T) {
+	descriptionRule := ruleNamed(t, "itemDescriptionLength")
+	rules := config.Default().Rules
+	for price, want := range map[string]int{
+		"5.35":  2,
+		"-5.35": 0, // A discount line earns nothing and costs nothing
+		"-0.01": 0,
+	} {
+		if got := descriptionRule.itemPoints(models.Item{ShortDescription: "Coupon", Price: price}, rules); got != want {
+			t.Errorf("price %s: %d points, want %d", price, got, want)
+		}
+	}
+
+	// The discount brings targetReceipt to a round 30.00; with the item-total check
+	// enforced the signed prices must add up to it
+	h := newTestHandler(t, func(c *config.Config) {
+		c.ValidationChecks = map[string]string{"itemTotal": severityError}
+	})
+	body := strings.Replace(targetReceipt, `"total": "35.35"`, `"total": "30.00"`, 1)
+	body = strings.Replace(body, `"price": "12.00"}`, `"price": "12.00"}, {"shortDescription": "Coupon", "price": "-5.35"}`, 1)
+	id := mustProcess(t, h, "alice", body, http.StatusCreated)
+
+	stored, ok := h.store.Get(id)
+	if !ok {
+		t.Fatalf("receipt %s was not stored", id)
+	}
+	want := map[string]int{
+		"retailerAlphanumeric":  6,
+		"roundDollarTotal":      50,
+		"quarterMultipleTotal":  25,
+		"itemPairs":             15, // Six items, the discount included
+		"itemDescriptionLength": 6,  // Unchanged by the discount
+		"oddPurchaseDay":        6,
+	}
+	for _, result := range stored.Breakdown {
+		if result.Points != want[result.Rule] {
+			t.Errorf("%s: %d points, want %d", result.Rule, result.Points, want[result.Rule])
+		}
+		if result.Rule == "itemDescriptionLength" && result.Items[5] != 0 {
+			t.Errorf("discount item earned %d description points", result.Items[5])
+		}
+	}
+	if stored.Points != 108 {
+		t.Errorf("points = %d, want 108", stored.Points)
+	}
+
+	// Prices that do not add up to the total, counting the discount, are rejected
+	mismatched := strings.Replace(body, `"-5.35"`, `"-5.34"`, 1)
+	if w := postReceipt(t, h, "alice", mismatched); w.Code != http.StatusBadRequest {
+		t.Errorf("mismatched discount: status %d, want 400", w.Code)
+	}
+}
+
+// itemsPriced returns one item per price, with descriptions left empty.
+func itemsPriced(prices ...string) []models.Item {
+	items := make([]models.Item, len(prices))
+	for i, price := range prices {
+		items[i].Price = price
+	}
+	return items
+}
+
+func TestDistinctPricesRule(t *testing.T) {
+	distinctRule := ruleNamed(t, "distinctPrices")
+	rules := config.Default().Rules
+	if got := distinctRule.points(&models.Receipt{Items: itemsPriced("1.00", "2.00")}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.DistinctPricePoints = 3
+	for _, tc := range []struct {
+		prices []string
+		want   int
+	}{
+		{nil, 0},
+		{[]string{"1.00"}, 3},
+		{[]string{"1.00", "2.00", "1.00"}, 6},
+		{[]string{"1.00", "01.00"}, 3}, // The same amount in cents
+		{[]string{"1.00", "-1.00"}, 6},
+		{[]string{"1.00", "abc"}, 3},
+	} {
+		if got := distinctRule.points(&models.Receipt{Items: itemsPriced(tc.prices...)}, rules); got != tc.want {
+			t.Errorf("prices %v: %d points, want %d", tc.prices, got, tc.want)
+		}
+	}
+}
+
+func TestHolidayPurchaseRule(t *testing.T) {
+	holidayRule := ruleNamed(t, "holidayPurchase")
+	rules := config.Default().Rules
+	rules.Holidays = []string{"12-25", "2022-11-24"}
+	if got := holidayRule.points(&models.Receipt{PurchaseDate: "2022-12-25"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.HolidayBonus = 15
+	for date, want := range map[string]int{
+		"2022-12-25": 15, // Recurring every year
+		"2031-12-25": 15,
+		"2022-11-24": 15, // A specific date
+		"2023-11-24": 0,
+		"2022-12-24": 0,
+		"12-25":      0,
+		"":           0,
+	} {
+		if got := holidayRule.points(&models.Receipt{PurchaseDate: date}, rules); got != want {
+			t.Errorf("date %q: %d points, want %d", date, got, want)
+		}
+	}
+}
+
+func TestRetailerVowelsRule(t *testing.T) {
+	vowelRule := ruleNamed(t, "retailerVowels")
+	rules := config.Default().Rules
+	if got := vowelRule.points(&models.Receipt{Retailer: "Target"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.RetailerVowelPoints = 2
+	for retailer, want := range map[string]int{
+		"Target":  4, // a, e
+		"":        0,
+		"TARGET":  4,
+		"Café":    4,
+		"M&M":     0,
+		"Aeiou Y": 10,
+	} {
+		if got := vowelRule.points(&models.Receipt{Retailer: retailer}, rules); got != want {
+			t.Errorf("retailer %q: %d points, want %d", retailer, got, want)
+		}
+	}
+}
+
+func TestUniformBasketRule(t *testing.T) {
+	uniformRule := ruleNamed(t, "uniformBasket")
+	rules := config.Default().Rules // At least 2 items
+	if got := uniformRule.points(&models.Receipt{Items: itemsPriced("1.00", "1.00")}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.UniformBasketBonus = 12
+	for _, tc := range []struct {
+		prices []string
+		want   int
+	}{
+		{nil, 0},
+		{[]string{"1.00"}, 0}, // Too few items
+		{[]string{"1.00", "1.00"}, 12},
+		{[]string{"1.00", "01.00", "1.00"}, 12},
+		{[]string{"1.00", "1.01"}, 0},
+		{[]string{"1.00", "abc"}, 0},
+	} {
+		if got := uniformRule.points(&models.Receipt{Items: itemsPriced(tc.prices...)}, rules); got != tc.want {
+			t.Errorf("prices %v: %d points, want %d", tc.prices, got, tc.want)
+		}
+	}
+
+	rules.UniformBasketMinItems = 3
+	if got := uniformRule.points(&models.Receipt{Items: itemsPriced("1.00", "1.00")}, rules); got != 0 {
+		t.Errorf("2 items with a minimum of 3: %d points, want 0", got)
+	}
+}
+
+func TestTotalModuloRule(t *testing.T) {
+	moduloRule := ruleNamed(t, "totalModulo")
+	rules := config.Default().Rules
+	rules.TotalModuloCents = 300
+	if got := moduloRule.points(&models.Receipt{Total: "9.00"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.TotalModuloBonus = 8
+	for total, want := range map[string]int{
+		"9.00":  8,
+		"3.00":  8,
+		"0.00":  8,
+		"-6.00": 8,
+		"9.01":  0,
+		"10.00": 0,
+		"abc":   0,
+	} {
+		if got := moduloRule.points(&models.Receipt{Total: total}, rules); got != want {
+			t.Errorf("total %s: %d points, want %d", total, got, want)
+		}
+	}
+
+	rules.TotalModuloCents = 0
+	if got := moduloRule.points(&models.Receipt{Total: "9.00"}, rules); got != 0 {
+		t.Errorf("zero modulus: %d points, want 0", got)
+	}
+}
+
+func TestEvenCentsItemsRule(t *testing.T) {
+	evenRule := ruleNamed(t, "evenCentsItems")
+	rules := config.Default().Rules
+	if got := evenRule.itemPoints(models.Item{Price: "1.26"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.EvenCentsItemPoints = 3
+	for price, want := range map[string]int{
+		"1.26":  3,
+		"1.00":  3,
+		"0.00":  3,
+		"-1.26": 3,
+		"1.25":  0,
+		"2.01":  0,
+		"abc":   0,
+	} {
+		if got := evenRule.itemPoints(models.Item{Price: price}, rules); got != want {
+			t.Errorf("price %s: %d points, want %d", price, got, want)
+		}
+	}
+}
+
+func TestDigitDescriptionsRule(t *testing.T) {
+	digitRule := ruleNamed(t, "digitDescriptions")
+	rules := config.Default().Rules
+	if got := digitRule.itemPoints(models.Item{ShortDescription: "Mountain Dew 12PK"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.DigitDescriptionPoints = 4
+	for description, want := range map[string]int{
+		"Mountain Dew 12PK":    4,
+		"SKU9":                 4,
+		"Milch ٣ Liter":        4, // Arabic-Indic three
+		"Emils Cheese Pizza":   0,
+		"Doritos Nacho Cheese": 0,
+		"":                     0,
+	} {
+		if got := digitRule.itemPoints(models.Item{ShortDescription: description}, rules); got != want {
+			t.Errorf("description %q: %d points, want %d", description, got, want)
+		}
+	}
+}
+
+func TestRetailerChecksumRule(t *testing.T) {
+	checksumRule := ruleNamed(t, "retailerChecksum")
+	rules := config.Default().Rules // Modulus 10
+	rules.RetailerChecksumTarget = 5
+	if got := checksumRule.points(&models.Receipt{Retailer: "Target"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.RetailerChecksumBonus = 20
+	for retailer, want := range map[string]int{
+		"Target":     20, // 84+97+114+103+101+116 = 615
+		"  Target  ": 20,
+		"target":     0, // 647
+		"M&M":        0, // 192
+		"":           0,
+	} {
+		if got := checksumRule.points(&models.Receipt{Retailer: retailer}, rules); got != want {
+			t.Errorf("retailer %q: %d points, want %d", retailer, got, want)
+		}
+	}
+
+	rules.RetailerChecksumModulus = 0
+	if got := checksumRule.points(&models.Receipt{Retailer: "Target"}, rules); got != 0 {
+		t.Errorf("zero modulus: %d points, want 0", got)
+	}
+}
+
+func TestLuckyTotalRule(t *testing.T) {
+	luckyRule := ruleNamed(t, "luckyTotal")
+	rules := config.Default().Rules // A run of 4 digits
+	if got := luckyRule.points(&models.Receipt{Total: "11.11"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.LuckyTotalBonus = 7
+	for total, want := range map[string]int{
+		"11.11":   7,
+		"12.34":   0,
+		"55.55":   7,
+		"-11.11":  7,
+		"1.11":    0, // A run of 3
+		"1111.00": 7,
+		"10.00":   0,
+		"abc":     0,
+	} {
+		if got := luckyRule.points(&models.Receipt{Total: total}, rules); got != want {
+			t.Errorf("total %s: %d points, want %d", total, got, want)
+		}
+	}
+
+	rules.LuckyTotalRunLength = 3
+	if got := luckyRule.points(&models.Receipt{Total: "1.11"}, rules); got != 7 {
+		t.Errorf("1.11 with a run length of 3: %d points, want 7", got)
+	}
+}
+
+func TestDescriptionLengthRule(t *testing.T) {
+	lengthRule := ruleNamed(t, "descriptionLength")
+	described := func(descriptions ...string) *models.Receipt {
+		r := &models.Receipt{Items: make([]models.Item, len(descriptions))}
+		for i, description := range descriptions {
+			r.Items[i].ShortDescription = description
+		}
+		return r
+	}
+	rules := config.Default().Rules // Every 10 characters
+	if got := lengthRule.points(described("Emils Cheese Pizza"), rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.DescriptionLengthPoints = 2
+	for _, tc := range []struct {
+		descriptions []string
+		want         int
+	}{
+		{nil, 0},
+		{[]string{"Pizza"}, 0},
+		{[]string{"Pizza", "Chips"}, 2}, // 10 characters across both
+		{[]string{"Emils Cheese Pizza"}, 2},
+		{[]string{"   Klarbrunn 12-PK 12 FL OZ  "}, 4}, // 24 once trimmed
+		{[]string{"Crème brû"}, 0},                     // 9 characters in 11 bytes
+	} {
+		if got := lengthRule.points(described(tc.descriptions...), rules); got != tc.want {
+			t.Errorf("descriptions %q: %d points, want %d", tc.descriptions, got, tc.want)
+		}
+	}
+
+	rules.DescriptionLengthChars = 0
+	if got := lengthRule.points(described("Emils Cheese Pizza"), rules); got != 0 {
+		t.Errorf("zero characters per award: %d points, want 0", got)
+	}
+}
+
+func TestRoundDollarItemsRule(t *testing.T) {
+	roundRule := ruleNamed(t, "roundDollarItems")
+	rules := config.Default().Rules
+	if got := roundRule.itemPoints(models.Item{Price: "12.00"}, rules); got != 0 {
+		t.Errorf("default configuration awards %d points, want the rule off", got)
+	}
+
+	rules.RoundDollarItemPoints = 5
+	for price, want := range map[string]int{
+		"12.00": 5,
+		"1.00":  5,
+		"12.01": 0,
+		"12.50": 0,
+		"0.00":  0, // Free items are not rewarded
+		"-5.00": 0, // Nor are discounts
+		"abc":   0,
+	} {
+		if got := roundRule.itemPoints(models.Item{Price: price}, rules); got != want {
+			t.Errorf("price %s: %d points, want %d", price, got, want)
+		}
+	}
+}
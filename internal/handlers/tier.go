@@ -0,0 +1,65 @@
+// tier.go
+// This file contains the loyalty tier handler, which maps a receipt's points to
+// the configured tier table.
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// GetTier handles the GET request to retrieve the loyalty tier of a receipt.
+// It responds with the tier the receipt's points fall into and that tier's
+// point range. Receipts scoring below the lowest tier have no tier.
+func (h *Handler) GetTier(w http.ResponseWriter, r *http.Request) {
+	// Verify JWT token from Authorization header; its claims decide which receipts are visible
+	claims, err := utils.ParseJWT(r)
+	if err != nil {
+		writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Retrieve the receipt from the store, rejecting malformed IDs
+	id, ok := receiptID(w, r)
+	if !ok {
+		return
+	}
+	receipt, exists, err := h.store.GetContext(r.Context(), id)
+	if err != nil {
+		writeContextError(w, r, err)
+		return
+	}
+	if !exists || !canAccess(claims, receipt) {
+		writeError(w, r, http.StatusNotFound, "No receipt found for that ID")
+		return
+	}
+
+	// Let caching clients skip the body when the receipt has not changed
+	if notModified(w, r, receipt.ModifiedAt) {
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, tierFor(receipt.Points, cfg.Tiers))
+}
+
+// tierFor finds the tier that points falls into. tiers must be sorted by
+// ascending MinPoints; the matching tier is the last one whose threshold the
+// points reach, and its range ends just below the next tier's threshold.
+func tierFor(points int, tiers []config.Tier) models.TierResponse {
+	resp := models.TierResponse{Points: points}
+	for i, tier := range tiers {
+		if points < tier.MinPoints {
+			break
+		}
+		resp.Tier, resp.MinPoints, resp.MaxPoints = tier.Name, tier.MinPoints, nil
+		if i+1 < len(tiers) {
+			maxPoints := tiers[i+1].MinPoints - 1
+			resp.MaxPoints = &maxPoints
+		}
+	}
+	return resp
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+)
+
+func TestTierFor(t *testing.T) {
+	tiers := config.Default().Tiers // Bronze from 0, Silver from 50, Gold from 100
+	for _, tc := range []struct {
+		points    int
+		tier      string
+		minPoints int
+		maxPoints int // Zero for the open-ended top tier
+	}{
+		{0, "Bronze", 0, 49},
+		{49, "Bronze", 0, 49},
+		{50, "Silver", 50, 99},
+		{99, "Silver", 50, 99},
+		{100, "Gold", 100, 0},
+		{1000, "Gold", 100, 0},
+	} {
+		got := tierFor(tc.points, tiers)
+		maxPoints := 0
+		if got.MaxPoints != nil {
+			maxPoints = *got.MaxPoints
+		}
+		if got.Points != tc.points || got.Tier != tc.tier || got.MinPoints != tc.minPoints || maxPoints != tc.maxPoints {
+			t.Errorf("tierFor(%d) = %s %d-%d, want %s %d-%d", tc.points, got.Tier, got.MinPoints, maxPoints, tc.tier, tc.minPoints, tc.maxPoints)
+		}
+	}
+
+	// Points below the lowest threshold fall into no tier
+	if got := tierFor(9, []config.Tier{{Name: "Bronze", MinPoints: 10}}); got.Tier != "" || got.MaxPoints != nil {
+		t.Errorf("tierFor(9) below the lowest tier = %+v, want no tier", got)
+	}
+}
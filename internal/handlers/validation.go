@@ -0,0 +1,82 @@
+// validation.go
+// This file defines the data-quality checks whose severity is configurable, so a
+// check can reject a receipt, merely warn about it, or be turned off.
+
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// Severities a data-quality check can be configured with
+const (
+	severityError   = "error"   // The receipt is rejected
+	severityWarning = "warning" // The receipt is processed and the problem reported as a warning
+	severityOff     = "off"     // The check is skipped
+)
+
+// qualityCheck is a named data-quality check run after a receipt passes validation.
+type qualityCheck struct {
+	name     string                        // Identifier used to configure the check's severity
+	severity string                        // Severity used when the configuration does not name the check
+	check    func(r *models.Receipt) error // Returns the problem found, or nil
+}
+
+// qualityChecks lists every configurable check in evaluation order.
+var qualityChecks = []qualityCheck{
+	// Reject individual items priced above the configured ceiling
+	{name: "maxItemPrice", severity: severityError, check: func(r *models.Receipt) error {
+		if cfg.MaxItemPriceCents <= 0 {
+			return nil
+		}
+		for idx, item := range r.Items {
+			if price, err := utils.ParseCents(item.Price); err == nil && price > cfg.MaxItemPriceCents {
+				return fmt.Errorf("item %d price %s exceeds the maximum of %s", idx, item.Price, utils.FormatCents(cfg.MaxItemPriceCents))
+			}
+		}
+		return nil
+	}},
+
+	// Flag receipts whose item prices do not add up to the total
+	{name: "itemTotal", severity: severityOff, check: func(r *models.Receipt) error {
+		var sum int64
+		for _, item := range r.Items {
+			price, err := utils.ParseCents(item.Price)
+			if err != nil {
+				return nil
+			}
+			sum += price
+		}
+		total, err := utils.ParseCents(r.Total)
+		if err != nil || sum == total {
+			return nil
+		}
+		return fmt.Errorf("item prices sum to %s but the total is %s", utils.FormatCents(sum), r.Total)
+	}},
+}
+
+// runQualityChecks runs every enabled data-quality check against the receipt. The
+// first problem from a check configured as an error is returned as the error, while
+// problems from checks configured as warnings are collected and returned.
+func runQualityChecks(r *models.Receipt) ([]string, error) {
+	var warnings []string
+	for _, qc := range qualityChecks {
+		severity := qc.severity
+		if configured, ok := cfg.ValidationChecks[qc.name]; ok {
+			severity = configured
+		}
+		if severity == severityOff {
+			continue
+		}
+		if err := qc.check(r); err != nil {
+			if severity != severityWarning {
+				return nil, err
+			}
+			warnings = append(warnings, err.Error())
+		}
+	}
+	return warnings, nil
+}
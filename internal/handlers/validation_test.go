@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+func TestMaxItemPrice(t *testing.T) {
+	// The most expensive item on targetReceipt is priced 12.25
+	for _, tc := range []struct {
+		name    string
+		ceiling int64
+		want    int
+		message string
+	}{
+		{"no ceiling", 0, http.StatusCreated, ""},
+		{"item above the ceiling", 1224, http.StatusBadRequest, "item 1 price 12.25 exceeds the maximum of 12.24"},
+		{"item at the ceiling", 1225, http.StatusCreated, ""},
+		{"items below the ceiling", 10000, http.StatusCreated, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.MaxItemPriceCents = tc.ceiling })
+			w := postReceipt(t, h, "alice", targetReceipt)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if got := strings.TrimSpace(w.Body.String()); tc.message != "" && got != tc.message {
+				t.Errorf("error = %q, want %q", got, tc.message)
+			}
+		})
+	}
+}
+
+func TestQualityWarningsAreReturnedAndStored(t *testing.T) {
+	const problem = "item prices sum to 35.35 but the total is 35.36"
+	for _, tc := range []struct {
+		severity string
+		want     int
+		warnings []string
+	}{
+		{severityOff, http.StatusCreated, nil},
+		{severityWarning, http.StatusCreated, []string{problem}},
+		{severityError, http.StatusBadRequest, nil},
+	} {
+		t.Run(tc.severity, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) {
+				c.ValidationChecks = map[string]string{"itemTotal": tc.severity}
+			})
+			w := postReceipt(t, h, "alice", withReceipt(t, "total", "35.36"))
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want != http.StatusCreated {
+				if got := strings.TrimSpace(w.Body.String()); got != problem {
+					t.Errorf("error = %q, want %q", got, problem)
+				}
+				return
+			}
+
+			var resp models.ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp.Warnings, tc.warnings) {
+				t.Errorf("response warnings = %q, want %q", resp.Warnings, tc.warnings)
+			}
+			stored, ok := h.store.Get(resp.ID)
+			if !ok {
+				t.Fatalf("receipt %s was not stored", resp.ID)
+			}
+			if !reflect.DeepEqual(stored.Warnings, tc.warnings) {
+				t.Errorf("stored warnings = %q, want %q", stored.Warnings, tc.warnings)
+			}
+		})
+	}
+}
+
+func TestMaxPurchaseAge(t *testing.T) {
+	purchased := time.Date(2022, 1, 1, 13, 1, 0, 0, time.UTC) // targetReceipt
+	const month = 30 * 24 * time.Hour
+	for _, tc := range []struct {
+		name   string
+		maxAge time.Duration
+		now    time.Time
+		want   int
+	}{
+		{"fresh", month, purchased.Add(time.Hour), http.StatusCreated},
+		{"exactly the maximum age", month, purchased.Add(month), http.StatusCreated},
+		{"over age", month, purchased.Add(month + time.Minute), http.StatusBadRequest},
+		{"no maximum", 0, purchased.AddDate(10, 0, 0), http.StatusCreated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) {
+				c.MaxPurchaseAge = tc.maxAge
+				c.DefaultTimezone = time.UTC
+			})
+			clock = fixedClock(tc.now)
+
+			w := postReceipt(t, h, "alice", targetReceipt)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if want := "purchase date/time is older than the maximum age of 720h0m0s"; tc.want != http.StatusCreated && strings.TrimSpace(w.Body.String()) != want {
+				t.Errorf("error = %q, want %q", strings.TrimSpace(w.Body.String()), want)
+			}
+		})
+	}
+}
+
+func TestPurchaseTimeWithSeconds(t *testing.T) {
+	for _, tc := range []struct {
+		purchaseTime string
+		allow        bool
+		want         int
+		stored       string // Purchase time kept on the stored receipt
+		points       int
+	}{
+		{"14:30:59", false, http.StatusBadRequest, "", 0},
+		{"14:30:59", true, http.StatusCreated, "14:30", 38},
+		{"15:59:59", true, http.StatusCreated, "15:59", 38},
+		{"16:00:30", true, http.StatusCreated, "16:00", 28}, // Truncated, not rounded, out of the afternoon window
+		{"14:30:60", true, http.StatusBadRequest, "", 0},
+		{"14:30", true, http.StatusCreated, "14:30", 38},
+	} {
+		t.Run(fmt.Sprintf("%s allow=%v", tc.purchaseTime, tc.allow), func(t *testing.T) {
+			h := newTestHandler(t, func(c *config.Config) { c.AllowPurchaseTimeSeconds = tc.allow })
+			w := postReceipt(t, h, "alice", withReceipt(t, "purchaseTime", tc.purchaseTime))
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d; body %q", w.Code, tc.want, w.Body.String())
+			}
+			if tc.want != http.StatusCreated {
+				return
+			}
+
+			var resp models.ProcessResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			stored, ok := h.store.Get(resp.ID)
+			if !ok {
+				t.Fatalf("receipt %s was not stored", resp.ID)
+			}
+			if stored.Receipt.PurchaseTime != tc.stored || stored.Points != tc.points {
+				t.Errorf("stored %s with %d points, want %s with %d", stored.Receipt.PurchaseTime, stored.Points, tc.stored, tc.points)
+			}
+		})
+	}
+}
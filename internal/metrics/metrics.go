@@ -0,0 +1,37 @@
+// metrics.go
+// This file defines the Prometheus metrics exported by the service at /metrics.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ReceiptsProcessed counts receipts newly stored by the process endpoints.
+	ReceiptsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "receipts_processed_total",
+		Help: "Number of receipts processed and stored.",
+	})
+
+	// ValidationFailures counts receipts rejected because they failed validation.
+	ValidationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "receipt_validation_failures_total",
+		Help: "Number of receipts rejected by validation or data-quality checks.",
+	})
+
+	// PointsAwarded sums the points awarded to newly stored receipts.
+	PointsAwarded = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "receipt_points_awarded_total",
+		Help: "Total points awarded to processed receipts.",
+	})
+
+	// RequestDuration records request latency in seconds, labeled by the matched
+	// route template, method and response status.
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests by handler.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler", "method", "code"})
+)
@@ -0,0 +1,39 @@
+// bodylimit.go
+// This file contains the middleware that limits the size of request bodies per route.
+
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gorilla/mux"
+)
+
+// versionPrefix matches the API version segment that starts a route template, such as "/v1"
+var versionPrefix = regexp.MustCompile(`^/v\d+`)
+
+// BodyLimit caps the request body of every route at def bytes, or at the limit that
+// routes gives its template with any version prefix removed, so "/receipts/process/batch"
+// covers both /v1/receipts/process/batch and the unprefixed path. Reading past the
+// limit fails with an *http.MaxBytesError, which handlers report as 413 Request
+// Entity Too Large. A limit of zero leaves the body unlimited. It must be installed
+// with Router.Use, where the matched route is known.
+func BodyLimit(def int64, routes map[string]int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := def
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					if routeLimit, ok := routes[versionPrefix.ReplaceAllString(template, "")]; ok {
+						limit = routeLimit
+					}
+				}
+			}
+			if limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
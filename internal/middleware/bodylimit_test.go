@@ -0,0 +1,60 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/handlers"
+	"github.com/saurabhag23/receipt-processor/internal/middleware"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+func TestBatchRouteAcceptsBodiesTheSingleRouteRejects(t *testing.T) {
+	handlers.Configure(config.Default())
+	h := handlers.NewHandler(store.NewInMemoryStore())
+	r := mux.NewRouter()
+	r.Use(middleware.BodyLimit(400, map[string]int64{"/receipts/process/batch": 4096}))
+	for _, prefix := range []string{"/v1", ""} {
+		r.HandleFunc(prefix+"/receipts/process", h.ProcessReceipt).Methods("POST")
+		r.HandleFunc(prefix+"/receipts/process/batch", h.ProcessReceiptBatch).Methods("POST")
+	}
+
+	token, err := utils.GenerateJWT("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	batchOf := func(n int) string {
+		receipts := make([]string, n)
+		for i := range receipts {
+			receipts[i] = receipt
+		}
+		return "[" + strings.Join(receipts, ",") + "]"
+	}
+
+	// receipt is over the default limit of 400 bytes and well under the batch route's 4096
+	for _, tc := range []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/v1/receipts/process", receipt, http.StatusRequestEntityTooLarge},
+		{"/v1/receipts/process/batch", batchOf(1), http.StatusOK},
+		{"/receipts/process/batch", batchOf(1), http.StatusOK},
+		{"/v1/receipts/process/batch", batchOf(10), http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s with %d bytes: status %d (%s), want %d", tc.path, len(tc.body), w.Code, strings.TrimSpace(w.Body.String()), tc.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// cors.go
+// This file contains the CORS middleware that lets browser clients call the API.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Values advertised to browsers for cross-origin requests
+const (
+	corsAllowMethods  = "GET, POST, DELETE"
+	corsAllowHeaders  = "Authorization, Content-Type, Content-Encoding, If-None-Match"
+	corsExposeHeaders = "ETag, X-Request-ID, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After"
+)
+
+// CORS adds Access-Control-Allow-* headers to requests from the allowed origins and
+// answers their OPTIONS preflight requests directly with 204 No Content. An origin of
+// "*" allows every origin. Requests from other origins pass through unchanged, so the
+// browser blocks them; authentication is still enforced by the handlers. An empty
+// list disables the middleware.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(allowedOrigins) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !originAllowed(origin, allowedOrigins) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Expose-Headers", corsExposeHeaders)
+
+			// Preflight requests never reach the router, which has no OPTIONS routes
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", corsAllowMethods)
+				h.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// originAllowed reports whether origin matches one of the allowed origins, ignoring case.
+func originAllowed(origin string, allowedOrigins []string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == "*" || strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
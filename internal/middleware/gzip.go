@@ -0,0 +1,166 @@
+// gzip.go
+// This file contains the middleware that decompresses gzip request bodies and
+// gzip-compresses responses.
+
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Gzip transparently decompresses request bodies sent with Content-Encoding: gzip,
+// answering an unreadable gzip body with 400 Bad Request and any other encoding
+// with 415 Unsupported Media Type. Responses to clients that send
+// Accept-Encoding: gzip are compressed once their body reaches minSize bytes;
+// smaller responses, which would barely shrink, are sent as they are. Handlers see
+// only uncompressed bodies, so body size limits apply to the decompressed JSON.
+func Gzip(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+			case "", "identity":
+			case "gzip":
+				body, err := gzip.NewReader(r.Body)
+				if err != nil {
+					http.Error(w, "Invalid gzip request body", http.StatusBadRequest)
+					return
+				}
+				defer body.Close()
+				r.Body = body
+				r.ContentLength = -1
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+			default:
+				http.Error(w, "Unsupported Content-Encoding", http.StatusUnsupportedMediaType)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize}
+			defer gw.close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows a gzip response.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// A quality of zero explicitly refuses the coding
+		for _, param := range strings.Split(params, ";") {
+			if key, value, _ := strings.Cut(param, "="); strings.TrimSpace(key) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter buffers the start of a response until it knows whether the body is
+// large enough to compress, then either compresses it or writes it unchanged.
+type gzipWriter struct {
+	http.ResponseWriter
+	minSize int
+	code    int          // Status passed to WriteHeader, written once the encoding is decided
+	buf     []byte       // Body written before the encoding was decided
+	decided bool         // Whether the status and encoding have been written
+	gz      *gzip.Writer // Compressor, when the response is being compressed
+}
+
+// WriteHeader records the status; it is written once the encoding is decided.
+// Statuses that never carry a body are written straight through.
+func (w *gzipWriter) WriteHeader(status int) {
+	if w.decided || w.code != 0 {
+		return
+	}
+	w.code = status
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decide(false)
+	}
+}
+
+// Write buffers b until minSize bytes have been written, then starts compressing.
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(w.Header().Get("Content-Encoding") == ""); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush compresses a pending streamed body and pushes what has been written so far
+// to the client.
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) > 0 && w.Header().Get("Content-Encoding") == "")
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// decide writes the status, compressing the rest of the response when compress is
+// set, and then writes the buffered body.
+func (w *gzipWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if compress {
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	var out io.Writer = w.ResponseWriter
+	if w.gz != nil {
+		out = w.gz
+	}
+	_, err := out.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// close sends a response too small to compress unchanged, or finishes the gzip stream.
+func (w *gzipWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *gzipWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
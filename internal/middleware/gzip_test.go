@@ -0,0 +1,117 @@
+package middleware_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/handlers"
+	"github.com/saurabhag23/receipt-processor/internal/middleware"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+const receipt = `{
+	"retailer": "Target",
+	"purchaseDate": "2022-01-01",
+	"purchaseTime": "13:01",
+	"items": [
+		{"shortDescription": "Mountain Dew 12PK", "price": "6.49"},
+		{"shortDescription": "Emils Cheese Pizza", "price": "12.25"},
+		{"shortDescription": "Knorr Creamy Chicken", "price": "1.26"},
+		{"shortDescription": "Doritos Nacho Cheese", "price": "3.35"},
+		{"shortDescription": "   Klarbrunn 12-PK 12 FL OZ  ", "price": "12.00"}
+	],
+	"total": "35.35"
+}`
+
+// gzipped returns s compressed with gzip.
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gunzipped decompresses a gzip response body.
+func gunzipped(t *testing.T, w *httptest.ResponseRecorder) []byte {
+	t.Helper()
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestGzipReceiptRoundTrip(t *testing.T) {
+	handlers.Configure(config.Default())
+	h := handlers.NewHandler(store.NewInMemoryStore())
+	r := mux.NewRouter()
+	r.Use(middleware.Gzip(0))
+	r.HandleFunc("/v1/receipts/process", h.ProcessReceipt).Methods("POST")
+	r.HandleFunc("/v1/receipts/{id}/points", h.GetPoints).Methods("GET")
+
+	token, err := utils.GenerateJWT("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/receipts/process", bytes.NewReader(gzipped(t, receipt)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	w := send(req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("process: status %d, body %q", w.Code, w.Body.String())
+	}
+	var processed struct{ ID string }
+	if err := json.Unmarshal(gunzipped(t, w), &processed); err != nil || processed.ID == "" {
+		t.Fatalf("process response: %+v, %v", processed, err)
+	}
+
+	w = send(httptest.NewRequest(http.MethodGet, "/v1/receipts/"+processed.ID+"/points", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("points: status %d, body %q", w.Code, w.Body.String())
+	}
+	var points struct{ Points int }
+	if err := json.Unmarshal(gunzipped(t, w), &points); err != nil || points.Points != 28 {
+		t.Errorf("points response: %+v, %v; want 28 points", points, err)
+	}
+
+	// Bodies that are not gzip, or use another encoding, never reach the handler
+	for encoding, want := range map[string]int{"gzip": http.StatusBadRequest, "br": http.StatusUnsupportedMediaType} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/receipts/process", strings.NewReader(receipt))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		if w := send(req); w.Code != want {
+			t.Errorf("Content-Encoding %s with a plain body: status %d, want %d", encoding, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// logging.go
+// This file contains the request logging middleware.
+
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// Logging writes one key=value line per request to logger, recording the method,
+// path, response status, bytes written, latency, request ID and the JWT subject
+// ("-" for unauthenticated requests). It should wrap every other handler so that
+// responses produced by other middleware, such as rate limiting, are logged too.
+func Logging(logger *log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			subject := "-"
+			if claims, err := utils.ParseJWT(r); err == nil && claims.Subject != "" {
+				subject = claims.Subject
+			}
+			requestID := w.Header().Get("X-Request-ID")
+			if requestID == "" {
+				requestID = "-"
+			}
+
+			logger.Printf("method=%s path=%s status=%d bytes=%d latency_ms=%s request_id=%s subject=%s",
+				r.Method, strconv.Quote(r.URL.Path), sw.status(), sw.bytes,
+				strconv.FormatFloat(float64(time.Since(start).Microseconds())/1000, 'f', 3, 64),
+				requestID, strconv.Quote(subject))
+		})
+	}
+}
+
+// statusWriter records the status code and body size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+// WriteHeader records the status before passing it on.
+func (w *statusWriter) WriteHeader(status int) {
+	if w.code == 0 {
+		w.code = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the body bytes; a write without WriteHeader implies 200 OK.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// status returns the recorded status, defaulting to 200 OK for empty responses.
+func (w *statusWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
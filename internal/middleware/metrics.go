@@ -0,0 +1,33 @@
+// metrics.go
+// This file contains the middleware that records request latency metrics.
+
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/saurabhag23/receipt-processor/internal/metrics"
+)
+
+// Metrics observes the latency of every request in the request duration histogram,
+// labeled by the route template (such as "/receipts/{id}/points") rather than the
+// raw path, so that receipt IDs do not create a time series each. It must be
+// installed with Router.Use, where the matched route is known.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		handler := "unmatched"
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				handler = template
+			}
+		}
+		metrics.RequestDuration.WithLabelValues(handler, r.Method, strconv.Itoa(sw.status())).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,133 @@
+// middleware.go
+// This file contains HTTP middleware shared by all routes of the receipt processing service.
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// contextKey is an unexported type for context keys defined in this package,
+// preventing collisions with keys from other packages.
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota // Context key for the request ID
+	startTimeKey                   // Context key for the time the request was received
+)
+
+// RequestID assigns every request a unique ID and records when it was received.
+// The ID is echoed in the X-Request-ID response header; a client-supplied ID is reused.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = context.WithValue(ctx, startTimeKey, time.Now())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetRequestID returns the request ID stored in ctx, or an empty string if none was set.
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// GetStartTime returns the time the request was received, or the zero time if unknown.
+func GetStartTime(ctx context.Context) time.Time {
+	t, _ := ctx.Value(startTimeKey).(time.Time)
+	return t
+}
+
+// Timeout gives each request's context a deadline d after the request arrives.
+// Handlers watch the context and give up with 503 Service Unavailable once it
+// expires, or stop early when the client disconnects. A d of zero disables it.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RetryAfter sets the Retry-After header to value on every 429 Too Many Requests and
+// 503 Service Unavailable response that does not already carry one, so that rate
+// limiting and unavailability responses advertise a consistent backoff. The value is
+// either a number of seconds or an HTTP-date. An empty value disables the middleware.
+func RetryAfter(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&retryAfterWriter{ResponseWriter: w, value: value}, r)
+		})
+	}
+}
+
+// retryAfterWriter adds the Retry-After header just before a 429 or 503 status is written.
+type retryAfterWriter struct {
+	http.ResponseWriter
+	value string
+}
+
+// WriteHeader sets Retry-After for throttling and unavailability statuses.
+func (w *retryAfterWriter) WriteHeader(status int) {
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		if w.Header().Get("Retry-After") == "" {
+			w.Header().Set("Retry-After", w.value)
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *retryAfterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Maintenance answers every request except the /healthz liveness check and
+// /metrics with 503 Service Unavailable while enabled, so the service can be taken
+// out of rotation without being stopped. /readyz is refused too, telling load
+// balancers to stop routing traffic here.
+func Maintenance(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/healthz" || r.URL.Path == "/metrics" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "Service Unavailable: down for maintenance", http.StatusServiceUnavailable)
+		})
+	}
+}
+
+// Deprecated marks responses from routes kept only for backwards compatibility. It
+// sets the Deprecation header and a Link header naming the same path under
+// successorPrefix, such as "/v1", as the successor version clients should move to.
+func Deprecated(successorPrefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successorPrefix+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
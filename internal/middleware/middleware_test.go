@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+)
+
+// ok answers every request with 200 OK.
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// serve sends a GET request for path to h and returns the response.
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRateLimitedResponsesCarryConfiguredRetryAfter(t *testing.T) {
+	h := RetryAfter("7")(RateLimit(config.RateLimit{RPS: 0.001, Burst: 1}, nil)(ok))
+
+	if w := serve(h, "/v1/receipts/process"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, want 200", w.Code)
+	}
+	w := serve(h, "/v1/receipts/process")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "7" {
+		t.Errorf("Retry-After = %q, want the configured 7", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestMaintenanceMode(t *testing.T) {
+	h := RetryAfter("120")(Maintenance(true)(ok))
+
+	for _, path := range []string{"/v1/receipts/process", "/receipts/abc/points", "/readyz"} {
+		w := serve(h, path)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: status %d, want 503", path, w.Code)
+		}
+		if got := w.Header().Get("Retry-After"); got != "120" {
+			t.Errorf("%s: Retry-After = %q, want 120", path, got)
+		}
+	}
+	for _, path := range []string{"/healthz", "/metrics"} {
+		if w := serve(h, path); w.Code != http.StatusOK {
+			t.Errorf("%s: status %d, want 200", path, w.Code)
+		}
+	}
+
+	if w := serve(Maintenance(false)(ok), "/v1/receipts/process"); w.Code != http.StatusOK {
+		t.Errorf("maintenance disabled: status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitHeadersCountDown(t *testing.T) {
+	h := RateLimit(config.RateLimit{RPS: 0.001, Burst: 3}, nil)(ok)
+
+	var lastReset int64
+	for i, want := range []struct {
+		code      int
+		remaining string
+	}{
+		{http.StatusOK, "2"},
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	} {
+		w := serve(h, "/v1/receipts/process")
+		if w.Code != want.code {
+			t.Errorf("request %d: status %d, want %d", i+1, w.Code, want.code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("request %d: X-RateLimit-Limit = %q, want 3", i+1, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != want.remaining {
+			t.Errorf("request %d: X-RateLimit-Remaining = %q, want %s", i+1, got, want.remaining)
+		}
+		reset, err := strconv.ParseInt(w.Header().Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil || reset < time.Now().Unix() || reset < lastReset {
+			t.Errorf("request %d: X-RateLimit-Reset = %q, want a time no earlier than the last", i+1, w.Header().Get("X-RateLimit-Reset"))
+		}
+		lastReset = reset
+	}
+}
+
+func TestRateLimitIsolatesTenants(t *testing.T) {
+	h := RateLimit(config.RateLimit{RPS: 0.001, Burst: 5}, map[string]config.RateLimit{
+		"acme":   {RPS: 0.001, Burst: 2},
+		"globex": {RPS: 0.001, Burst: 2},
+	})(ok)
+
+	send := func(user, tenant string) int {
+		t.Helper()
+		token, _, err := utils.IssueTenantJWT(user, utils.RoleUser, tenant, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/v1/receipts", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Two acme users share acme's bucket of 2 and exhaust it
+	for i, user := range []string{"alice", "bob"} {
+		if code := send(user, "acme"); code != http.StatusOK {
+			t.Fatalf("acme request %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := send("carol", "acme"); code != http.StatusTooManyRequests {
+		t.Errorf("third acme request: status %d, want 429", code)
+	}
+
+	// globex, from the same IP address, still has its own full bucket
+	for i := 0; i < 2; i++ {
+		if code := send("dave", "globex"); code != http.StatusOK {
+			t.Errorf("globex request %d: status %d, want 200", i+1, code)
+		}
+	}
+	if code := send("dave", "globex"); code != http.StatusTooManyRequests {
+		t.Errorf("third globex request: status %d, want 429", code)
+	}
+}
@@ -0,0 +1,144 @@
+// ratelimit.go
+// This file contains the per-client rate limiting middleware.
+
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/config"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+	"golang.org/x/time/rate"
+)
+
+// minSweepInterval is the shortest time between garbage collections of idle buckets
+const minSweepInterval = time.Minute
+
+// RateLimit gives each client a token bucket that holds up to Burst requests and
+// refills at RPS requests per second, answering requests that find the bucket empty
+// with 429 Too Many Requests; the RetryAfter middleware adds the configured
+// Retry-After header, so every 429 advertises the same backoff. Requests carrying a valid JWT
+// are keyed by its tenant claim, so a tenant's clients share one bucket sized by
+// tenantLimits (falling back to def) and one tenant cannot starve the others, or
+// else by its subject; unauthenticated clients are keyed by IP address. Every
+// limited response carries X-RateLimit-Limit (the burst), X-RateLimit-Remaining and
+// X-RateLimit-Reset (Unix seconds at which the bucket is full again) so clients can
+// throttle themselves. Clients whose limit has a zero rate are not limited.
+func RateLimit(def config.RateLimit, tenantLimits map[string]config.RateLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if def.RPS <= 0 && len(tenantLimits) == 0 {
+			return next
+		}
+		limiter := newBucketLimiter()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, limit := rateLimitKey(r, def, tenantLimits)
+			if limit.RPS <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			allowed, remaining, reset := limiter.allow(key, limit, time.Now())
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if !allowed {
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bucketLimiter holds one token bucket per client.
+type bucketLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket // Bucket of each client, guarded by mu
+	lastSweep time.Time          // When idle buckets were last removed, guarded by mu
+}
+
+// bucket is one client's token bucket and when it was last used.
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newBucketLimiter() *bucketLimiter {
+	return &bucketLimiter{buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from key's bucket at now and reports whether one was
+// available, how many remain and when the bucket will be full again.
+func (l *bucketLimiter) allow(key string, limit config.RateLimit, now time.Time) (allowed bool, remaining int, reset time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	allowed = b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+	remaining = max(int(math.Floor(tokens)), 0)
+	reset = now.Add(secondsToDuration((float64(limit.Burst) - tokens) / limit.RPS))
+	return allowed, remaining, reset
+}
+
+// sweep removes buckets idle long enough to have refilled completely, as a fresh
+// bucket would behave identically, so memory does not grow with every client ever
+// seen. It runs at most once per minSweepInterval. Callers must hold mu.
+func (l *bucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < minSweepInterval {
+		return
+	}
+	for key, b := range l.buckets {
+		refill := secondsToDuration(float64(b.limiter.Burst()) / float64(b.limiter.Limit()))
+		if now.Sub(b.lastSeen) > refill {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// secondsToDuration converts a non-negative number of seconds to a Duration.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(seconds, 0) * float64(time.Second))
+}
+
+// rateLimitKey returns the bucket key for r and the limit that applies to it: the
+// tenant claim of a valid JWT when present, then its subject, and otherwise the
+// client IP.
+func rateLimitKey(r *http.Request, def config.RateLimit, tenantLimits map[string]config.RateLimit) (string, config.RateLimit) {
+	claims, err := utils.ParseJWT(r)
+	switch {
+	case err == nil && claims.Tenant != "":
+		if limit, ok := tenantLimits[claims.Tenant]; ok {
+			return "tenant:" + claims.Tenant, limit
+		}
+		return "tenant:" + claims.Tenant, def
+	case err == nil && claims.Subject != "":
+		return "user:" + claims.Subject, def
+	default:
+		return "ip:" + clientIP(r), def
+	}
+}
+
+// clientIP returns the IP address of the client that sent r.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
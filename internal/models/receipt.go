@@ -1,14 +1,18 @@
 // models.go
 package models
 
+import "time"
+
 // Receipt represents the main structure of a receipt submitted for processing.
 // It includes information about the retailer, purchase date and time, items, and total amount.
 type Receipt struct {
-    Retailer     string `json:"retailer"`     // The name of the retailer or store
-    PurchaseDate string `json:"purchaseDate"` // The date of purchase (expected format: YYYY-MM-DD)
-    PurchaseTime string `json:"purchaseTime"` // The time of purchase (expected format: HH:MM in 24-hour format)
-    Items        []Item `json:"items"`        // List of items in the receipt
-    Total        string `json:"total"`        // Total amount paid, formatted as a string (expected format: 0.00)
+    Retailer     string `json:"retailer"`           // The name of the retailer or store
+    PurchaseDate string `json:"purchaseDate"`       // The date of purchase (expected format: YYYY-MM-DD)
+    PurchaseTime string `json:"purchaseTime"`       // The time of purchase (expected format: HH:MM in 24-hour format)
+    Items        []Item `json:"items"`              // List of items in the receipt
+    Total        string `json:"total"`              // Total amount paid, formatted as a string (expected format: 0.00)
+    Currency     string `json:"currency,omitempty"` // Optional ISO-4217 currency code, alphabetic ("USD") or numeric ("840")
+    Timezone     string `json:"timezone,omitempty"` // Optional IANA time zone of the purchase date and time, e.g. "America/New_York"
 }
 
 // Item represents a single item on the receipt.
@@ -20,7 +24,37 @@ type Item struct {
 
 // ProcessedReceipt represents a receipt after processing.
 // It includes a unique ID and the total points awarded based on the receipt rules.
+// Its JSON form is the full internal state, exposed only through the admin API.
 type ProcessedReceipt struct {
-    ID     string // Unique identifier for the processed receipt
-    Points int    // Points awarded to the receipt based on various rules
+    ID             string       `json:"id"`                 // Unique identifier for the processed receipt
+    Points         int          `json:"points"`             // Points awarded to the receipt based on various rules
+    Owner          string       `json:"owner"`              // Subject of the JWT that submitted the receipt
+    Receipt        Receipt      `json:"receipt"`            // The original receipt as submitted
+    Sequence       uint64       `json:"sequence"`           // Monotonic insertion order, used for stable pagination
+    Breakdown      []RuleResult `json:"breakdown"`          // Points awarded by each rule that applied to the receipt
+    PurchasedAt    time.Time    `json:"purchasedAt"`        // When the receipt was purchased, in UTC
+    PurchaseOffset string       `json:"purchaseOffset"`     // UTC offset the purchase date and time were written in, e.g. "-05:00"
+    ProcessedAt    time.Time    `json:"processedAt"`        // When the receipt was first processed and stored
+    ModifiedAt     time.Time    `json:"modifiedAt"`         // When the receipt was stored or last changed
+    ReceiptHash    string       `json:"receiptHash"`        // SHA-256 of the canonicalized original receipt
+    Warnings       []string     `json:"warnings,omitempty"` // Non-fatal data-quality problems found during validation
+    RawBody        []byte       `json:"rawBody,omitempty"`  // Exact bytes submitted for the receipt, base64 in JSON, when raw bodies are stored
+}
+
+// RuleResult records the points a single scoring rule awarded to a receipt.
+type RuleResult struct {
+    Rule        string      `json:"rule"`            // Identifier of the rule
+    Points      int         `json:"points"`          // Points the rule awarded
+    Description string      `json:"description"`     // Human-readable explanation of the rule
+    Items       map[int]int `json:"items,omitempty"` // Points awarded to each item, keyed by item index, for item-level rules
+}
+
+// ReceiptSummary is the condensed view of a processed receipt returned by listings.
+type ReceiptSummary struct {
+    ID           string    `json:"id"`           // Unique identifier for the processed receipt
+    Retailer     string    `json:"retailer"`     // The name of the retailer or store
+    PurchaseDate string    `json:"purchaseDate"` // The date of purchase
+    Total        string    `json:"total"`        // Total amount paid
+    Points       int       `json:"points"`       // Points awarded to the receipt
+    ProcessedAt  time.Time `json:"processedAt"`  // When the receipt was processed
 }
@@ -0,0 +1,64 @@
+// responses.go
+package models
+
+import "time"
+
+// The response types below define the JSON bodies returned by the API. Using
+// structs rather than maps fixes the field order, so encoded responses are
+// byte-for-byte stable.
+
+// ProcessResponse is returned after a receipt has been processed.
+type ProcessResponse struct {
+	ID           string   `json:"id"`                     // Unique identifier assigned to the processed receipt
+	ReceiptHash  string   `json:"receiptHash"`            // SHA-256 of the canonicalized receipt, for client-side integrity checks
+	ProcessingMs *float64 `json:"processingMs,omitempty"` // Validation, scoring and storage time, when debug timing is enabled
+	Warnings     []string `json:"warnings,omitempty"`     // Non-fatal data-quality problems found in the receipt
+}
+
+// PointsResponse is returned when retrieving the points for a receipt.
+type PointsResponse struct {
+	Points int         `json:"points"`          // Points awarded to the receipt
+	Items  map[int]int `json:"items,omitempty"` // Points attributable to each item, keyed by item index, when requested
+}
+
+// StringPointsResponse is PointsResponse with the points encoded as a quoted string,
+// such as {"points":"109"}, for clients that parse JSON numbers as floats.
+type StringPointsResponse struct {
+	Points int         `json:"points,string"`   // Points awarded to the receipt
+	Items  map[int]int `json:"items,omitempty"` // Points attributable to each item, keyed by item index, when requested
+}
+
+// ReceiptResponse is the full stored receipt: the original fields as submitted,
+// plus its generated ID, points, canonical hash and highest-priced item.
+type ReceiptResponse struct {
+	ID string `json:"id"` // Unique identifier for the processed receipt
+	Receipt
+	Points         int        `json:"points"`                   // Points awarded to the receipt
+	ReceiptHash    string     `json:"receiptHash"`              // SHA-256 of the canonicalized receipt
+	TopItem        *Item      `json:"topItem,omitempty"`        // Highest-priced item, the first one on ties
+	PurchasedAt    *time.Time `json:"purchasedAt,omitempty"`    // When the receipt was purchased, in UTC; omitted for receipts stored without it
+	PurchaseOffset string     `json:"purchaseOffset,omitempty"` // UTC offset the purchase date and time were written in
+	ProcessedAt    time.Time  `json:"processedAt"`              // When the receipt was processed, in RFC 3339 format
+}
+
+// TierResponse is returned when retrieving the loyalty tier of a receipt.
+type TierResponse struct {
+	Points    int    `json:"points"`              // Points awarded to the receipt
+	Tier      string `json:"tier,omitempty"`      // Name of the tier the points fall into, omitted below the lowest tier
+	MinPoints int    `json:"minPoints"`           // Fewest points that earn the tier
+	MaxPoints *int   `json:"maxPoints,omitempty"` // Most points that stay in the tier, omitted for the top tier
+}
+
+// ReceiptListResponse is a single page of receipt summaries.
+type ReceiptListResponse struct {
+	Receipts   []ReceiptSummary `json:"receipts"`             // Receipts on this page, in insertion order
+	NextCursor string           `json:"nextCursor,omitempty"` // Cursor for the next page, omitted on the last page
+	Total      int              `json:"total"`                // Number of receipts matching the filters across all pages
+}
+
+// BatchResult reports the outcome of one receipt in a batch submission.
+type BatchResult struct {
+	Index int    `json:"index"`           // Position of the receipt in the submitted array
+	ID    string `json:"id,omitempty"`    // Identifier assigned to the receipt when it was processed
+	Error string `json:"error,omitempty"` // Reason the receipt was rejected
+}
@@ -0,0 +1,363 @@
+// bolt.go
+// This file implements a durable ReceiptStore backed by an embedded BoltDB file,
+// so processed receipts survive restarts.
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// receiptsBucket is the BoltDB bucket holding JSON-encoded receipts keyed by ID.
+var receiptsBucket = []byte("receipts")
+
+// hashesBucket is the BoltDB bucket indexing receipt IDs by dedupeKey, the owner
+// together with the ReceiptHash.
+var hashesBucket = []byte("receipt_owner_hashes")
+
+// datesBucket is the BoltDB bucket indexing receipts by owner and purchase date.
+// Each key is the dateKey followed by a NUL and the receipt ID, so one owner's
+// receipts for a date share a key prefix; values are empty.
+var datesBucket = []byte("receipt_owner_dates")
+
+// legacyHashesBucket is the hash index of earlier versions, keyed by ReceiptHash
+// alone. It is replaced by hashesBucket when a store is opened.
+var legacyHashesBucket = []byte("receipt_hashes")
+
+// BoltStore is a ReceiptStore persisted to a BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) the BoltDB file at path.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open bolt store: %w", err)
+	}
+
+	// Make sure the receipts and index buckets exist before serving requests,
+	// building each index from the stored receipts when it is new
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(receiptsBucket); err != nil {
+			return err
+		}
+		if tx.Bucket(legacyHashesBucket) != nil {
+			if err := tx.DeleteBucket(legacyHashesBucket); err != nil {
+				return err
+			}
+		}
+		for _, index := range []struct {
+			bucket []byte
+			add    func(*bolt.Tx, string, *models.ProcessedReceipt) error
+		}{
+			{hashesBucket, indexHash},
+			{datesBucket, indexDate},
+		} {
+			if tx.Bucket(index.bucket) != nil {
+				continue
+			}
+			if _, err := tx.CreateBucket(index.bucket); err != nil {
+				return err
+			}
+			if err := tx.Bucket(receiptsBucket).ForEach(func(k, v []byte) error {
+				var receipt models.ProcessedReceipt
+				if err := json.Unmarshal(v, &receipt); err != nil {
+					log.Printf("bolt store: not indexing undecodable receipt %s: %v", k, err)
+					return nil
+				}
+				return index.add(tx, string(k), &receipt)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create buckets: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Save stores the receipt under id, assigning it the next insertion sequence number.
+func (s *BoltStore) Save(id string, receipt *models.ProcessedReceipt) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(receiptsBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		receipt.Sequence = seq
+		if err := putReceipt(bucket, id, receipt); err != nil {
+			return err
+		}
+		if err := indexHash(tx, id, receipt); err != nil {
+			return err
+		}
+		return indexDate(tx, id, receipt)
+	})
+}
+
+// SaveContext stores the receipt like Save unless ctx is already done. The write
+// transaction itself is not interrupted once it has begun.
+func (s *BoltStore) SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error {
+	return saveContext(ctx, s, id, receipt)
+}
+
+// Get returns the receipt stored under id and whether it exists. Records that
+// cannot be decoded are logged and reported as missing.
+func (s *BoltStore) Get(id string) (*models.ProcessedReceipt, bool) {
+	var receipt *models.ProcessedReceipt
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(receiptsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		receipt = &models.ProcessedReceipt{}
+		return json.Unmarshal(data, receipt)
+	})
+	if err != nil {
+		log.Printf("bolt store: read receipt %s: %v", id, err)
+		return nil, false
+	}
+	return receipt, receipt != nil
+}
+
+// GetContext returns the receipt stored under id like Get unless ctx is already done.
+func (s *BoltStore) GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	return getContext(ctx, s, id)
+}
+
+// GetMany returns the receipts stored under ids, read in a single transaction.
+// Records that cannot be decoded are logged and reported as missing.
+func (s *BoltStore) GetMany(ids []string) map[string]*models.ProcessedReceipt {
+	found := make(map[string]*models.ProcessedReceipt, len(ids))
+	s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(receiptsBucket)
+		for _, id := range ids {
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				continue
+			}
+			receipt := &models.ProcessedReceipt{}
+			if err := json.Unmarshal(data, receipt); err != nil {
+				log.Printf("bolt store: read receipt %s: %v", id, err)
+				continue
+			}
+			found[id] = receipt
+		}
+		return nil
+	})
+	return found
+}
+
+// GetManyContext returns the receipts stored under ids like GetMany unless ctx is already done.
+func (s *BoltStore) GetManyContext(ctx context.Context, ids []string) (map[string]*models.ProcessedReceipt, error) {
+	return getManyContext(ctx, s, ids)
+}
+
+// GetByHash returns owner's receipt whose ReceiptHash is hash, using the hash index bucket.
+func (s *BoltStore) GetByHash(owner, hash string) (*models.ProcessedReceipt, bool) {
+	var id []byte
+	s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(hashesBucket).Get([]byte(dedupeKey(owner, hash))); v != nil {
+			id = append(id, v...)
+		}
+		return nil
+	})
+	if id == nil {
+		return nil, false
+	}
+	return s.Get(string(id))
+}
+
+// GetByDate returns owner's receipts with the given purchase date, reading the
+// IDs under their key prefix in the date index bucket in the same transaction as
+// the receipts. Records that cannot be decoded are logged and skipped.
+func (s *BoltStore) GetByDate(owner, date string) []*models.ProcessedReceipt {
+	var found []*models.ProcessedReceipt
+	err := s.db.View(func(tx *bolt.Tx) error {
+		receipts := tx.Bucket(receiptsBucket)
+		prefix := []byte(dateKey(owner, date) + "\x00")
+		c := tx.Bucket(datesBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			id := k[len(prefix):]
+			data := receipts.Get(id)
+			if data == nil {
+				continue
+			}
+			receipt := &models.ProcessedReceipt{}
+			if err := json.Unmarshal(data, receipt); err != nil {
+				log.Printf("bolt store: skipping undecodable receipt %s: %v", id, err)
+				continue
+			}
+			found = append(found, receipt)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("bolt store: read receipts by date: %v", err)
+	}
+	return found
+}
+
+// Update applies fn to the stored receipt and writes it back in a single transaction.
+func (s *BoltStore) Update(id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error) {
+	var receipt *models.ProcessedReceipt
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(receiptsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		receipt = &models.ProcessedReceipt{}
+		if err := json.Unmarshal(data, receipt); err != nil {
+			return err
+		}
+		previous := *receipt
+		fn(receipt)
+		if err := putReceipt(bucket, id, receipt); err != nil {
+			return err
+		}
+		if reindex(&previous, receipt) {
+			if err := unindexHash(tx, id, &previous); err != nil {
+				return err
+			}
+			if err := unindexDate(tx, id, &previous); err != nil {
+				return err
+			}
+			if err := indexHash(tx, id, receipt); err != nil {
+				return err
+			}
+			return indexDate(tx, id, receipt)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Delete removes the receipt stored under id.
+func (s *BoltStore) Delete(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(receiptsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		var receipt models.ProcessedReceipt
+		if err := json.Unmarshal(data, &receipt); err == nil {
+			if err := unindexHash(tx, id, &receipt); err != nil {
+				return err
+			}
+			if err := unindexDate(tx, id, &receipt); err != nil {
+				return err
+			}
+		}
+		return bucket.Delete([]byte(id))
+	})
+}
+
+// DeleteContext removes the receipt stored under id like Delete unless ctx is already done.
+func (s *BoltStore) DeleteContext(ctx context.Context, id string) error {
+	return deleteContext(ctx, s, id)
+}
+
+// Range calls fn for every stored receipt, in key order, until fn returns false.
+// Records that cannot be decoded are logged and skipped.
+func (s *BoltStore) Range(fn func(*models.ProcessedReceipt) bool) {
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(receiptsBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			receipt := &models.ProcessedReceipt{}
+			if err := json.Unmarshal(v, receipt); err != nil {
+				log.Printf("bolt store: skipping undecodable receipt %s: %v", k, err)
+				continue
+			}
+			if !fn(receipt) {
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("bolt store: range receipts: %v", err)
+	}
+}
+
+// RangeContext calls fn like Range, checking ctx before each receipt, so a long
+// scan closes its read transaction soon after the request is abandoned.
+func (s *BoltStore) RangeContext(ctx context.Context, fn func(*models.ProcessedReceipt) bool) error {
+	return rangeContext(ctx, s, fn)
+}
+
+// Ping checks that the database can be read.
+func (s *BoltStore) Ping() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(receiptsBucket) == nil {
+			return fmt.Errorf("receipts bucket is missing")
+		}
+		return nil
+	})
+}
+
+// Close releases the database file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// putReceipt JSON-encodes the receipt into bucket under id.
+func putReceipt(bucket *bolt.Bucket, id string, receipt *models.ProcessedReceipt) error {
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(id), data)
+}
+
+// indexHash records id as the receipt with the dedupe key of receipt. Receipts
+// without a hash are not indexed.
+func indexHash(tx *bolt.Tx, id string, receipt *models.ProcessedReceipt) error {
+	if receipt.ReceiptHash == "" {
+		return nil
+	}
+	return tx.Bucket(hashesBucket).Put([]byte(dedupeKey(receipt.Owner, receipt.ReceiptHash)), []byte(id))
+}
+
+// unindexHash removes the hash index entry of receipt, stored under id, leaving
+// entries that point at other receipts.
+func unindexHash(tx *bolt.Tx, id string, receipt *models.ProcessedReceipt) error {
+	bucket := tx.Bucket(hashesBucket)
+	key := []byte(dedupeKey(receipt.Owner, receipt.ReceiptHash))
+	if string(bucket.Get(key)) != id {
+		return nil
+	}
+	return bucket.Delete(key)
+}
+
+// dateIndexKey is the date index bucket key recording that owner's receipt id has
+// the given purchase date.
+func dateIndexKey(id string, receipt *models.ProcessedReceipt) []byte {
+	return []byte(dateKey(receipt.Owner, receipt.Receipt.PurchaseDate) + "\x00" + id)
+}
+
+// indexDate records receipt, stored under id, in the date index.
+func indexDate(tx *bolt.Tx, id string, receipt *models.ProcessedReceipt) error {
+	return tx.Bucket(datesBucket).Put(dateIndexKey(id, receipt), []byte{})
+}
+
+// unindexDate removes receipt, stored under id, from the date index.
+func unindexDate(tx *bolt.Tx, id string, receipt *models.ProcessedReceipt) error {
+	return tx.Bucket(datesBucket).Delete(dateIndexKey(id, receipt))
+}
@@ -0,0 +1,209 @@
+// dual.go
+// This file implements a ReceiptStore that writes to two stores at once, used to
+// migrate between backends without downtime.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+// DualStore is a ReceiptStore that writes every change to both a primary and a
+// secondary store and reads from the primary. With read fallback enabled, an ID
+// missing from the primary is looked up in the secondary. Any read on which the two
+// stores disagree is logged so the migration can be validated before the secondary
+// is retired.
+type DualStore struct {
+	primary   ReceiptStore // Store that serves reads, usually the migration target
+	secondary ReceiptStore // Store kept in sync with the primary, usually the migration source
+	fallback  bool         // Read from the secondary when the primary has no receipt for an ID
+}
+
+// NewDualStore creates a DualStore over primary and secondary.
+func NewDualStore(primary, secondary ReceiptStore, fallback bool) *DualStore {
+	return &DualStore{primary: primary, secondary: secondary, fallback: fallback}
+}
+
+// Save stores the receipt in both stores. A failure in the primary fails the save,
+// while a failure in the secondary is only logged.
+func (s *DualStore) Save(id string, receipt *models.ProcessedReceipt) error {
+	// Each store assigns its own sequence number, so the secondary gets its own copy
+	copied := *receipt
+	if err := s.primary.Save(id, receipt); err != nil {
+		return err
+	}
+	if err := s.secondary.Save(id, &copied); err != nil {
+		log.Printf("dual store: secondary save of receipt %s failed: %v", id, err)
+	}
+	return nil
+}
+
+// SaveContext stores the receipt in both stores like Save unless ctx is already done.
+func (s *DualStore) SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error {
+	return saveContext(ctx, s, id, receipt)
+}
+
+// Get returns the receipt from the primary store, falling back to the secondary
+// when enabled. Receipts that are missing from either store or differ between them
+// are logged as discrepancies.
+func (s *DualStore) Get(id string) (*models.ProcessedReceipt, bool) {
+	receipt, ok := s.primary.Get(id)
+	other, otherOK := s.secondary.Get(id)
+
+	switch {
+	case ok && !otherOK:
+		log.Printf("dual store: receipt %s is missing from the secondary store", id)
+	case !ok && otherOK:
+		log.Printf("dual store: receipt %s is missing from the primary store", id)
+		if s.fallback {
+			return other, true
+		}
+	case ok && otherOK && (receipt.Points != other.Points || receipt.ReceiptHash != other.ReceiptHash):
+		log.Printf("dual store: receipt %s differs between stores (points %d vs %d)", id, receipt.Points, other.Points)
+	}
+	return receipt, ok
+}
+
+// GetContext returns the receipt like Get unless ctx is already done.
+func (s *DualStore) GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	return getContext(ctx, s, id)
+}
+
+// GetMany returns the receipts from the primary store, looking up the IDs missing
+// from it in the secondary when fallback is enabled.
+func (s *DualStore) GetMany(ids []string) map[string]*models.ProcessedReceipt {
+	found := s.primary.GetMany(ids)
+	if !s.fallback || len(found) == len(ids) {
+		return found
+	}
+	var missing []string
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	for id, receipt := range s.secondary.GetMany(missing) {
+		log.Printf("dual store: receipt %s is missing from the primary store", id)
+		found[id] = receipt
+	}
+	return found
+}
+
+// GetManyContext returns the receipts stored under ids like GetMany unless ctx is already done.
+func (s *DualStore) GetManyContext(ctx context.Context, ids []string) (map[string]*models.ProcessedReceipt, error) {
+	return getManyContext(ctx, s, ids)
+}
+
+// GetByHash returns owner's receipt with the given hash from the primary store,
+// falling back to the secondary when enabled.
+func (s *DualStore) GetByHash(owner, hash string) (*models.ProcessedReceipt, bool) {
+	if receipt, ok := s.primary.GetByHash(owner, hash); ok {
+		return receipt, true
+	}
+	if !s.fallback {
+		return nil, false
+	}
+	return s.secondary.GetByHash(owner, hash)
+}
+
+// GetByDate returns owner's receipts with the given purchase date from the primary
+// store, adding those found only in the secondary when fallback is enabled.
+func (s *DualStore) GetByDate(owner, date string) []*models.ProcessedReceipt {
+	found := s.primary.GetByDate(owner, date)
+	if !s.fallback {
+		return found
+	}
+	seen := make(map[string]bool, len(found))
+	for _, receipt := range found {
+		seen[receipt.ID] = true
+	}
+	for _, receipt := range s.secondary.GetByDate(owner, date) {
+		if !seen[receipt.ID] {
+			found = append(found, receipt)
+		}
+	}
+	return found
+}
+
+// Update applies fn in the primary store and writes the resulting snapshot to the
+// secondary, returning the primary's snapshot. fn runs only once, so state it
+// records reflects the receipt that was returned. The secondary keeps its own
+// sequence number; a receipt missing from it is saved there and logged.
+func (s *DualStore) Update(id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error) {
+	receipt, err := s.primary.Update(id, fn)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.secondary.Update(id, func(other *models.ProcessedReceipt) {
+		sequence := other.Sequence
+		*other = *receipt
+		other.Sequence = sequence
+	})
+	if errors.Is(err, ErrNotFound) {
+		log.Printf("dual store: receipt %s is missing from the secondary store, saving it", id)
+		copied := *receipt
+		err = s.secondary.Save(id, &copied)
+	}
+	if err != nil {
+		log.Printf("dual store: secondary update of receipt %s failed: %v", id, err)
+	}
+	return receipt, nil
+}
+
+// Delete removes the receipt from both stores. ErrNotFound is returned only when
+// neither store held the receipt.
+func (s *DualStore) Delete(id string) error {
+	err := s.primary.Delete(id)
+	otherErr := s.secondary.Delete(id)
+
+	if errors.Is(err, ErrNotFound) && errors.Is(otherErr, ErrNotFound) {
+		return ErrNotFound
+	}
+	if otherErr != nil && !errors.Is(otherErr, ErrNotFound) {
+		log.Printf("dual store: secondary delete of receipt %s failed: %v", id, otherErr)
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// DeleteContext removes the receipt stored under id like Delete unless ctx is already done.
+func (s *DualStore) DeleteContext(ctx context.Context, id string) error {
+	return deleteContext(ctx, s, id)
+}
+
+// Range calls fn for every receipt in the primary store until fn returns false.
+func (s *DualStore) Range(fn func(*models.ProcessedReceipt) bool) {
+	s.primary.Range(fn)
+}
+
+// RangeContext calls fn for every receipt in the primary store like Range,
+// checking ctx before each receipt.
+func (s *DualStore) RangeContext(ctx context.Context, fn func(*models.ProcessedReceipt) bool) error {
+	return s.primary.RangeContext(ctx, fn)
+}
+
+// Ping checks that both stores are reachable.
+func (s *DualStore) Ping() error {
+	if err := s.primary.Ping(); err != nil {
+		return err
+	}
+	return s.secondary.Ping()
+}
+
+// Close closes whichever of the two stores hold resources.
+func (s *DualStore) Close() error {
+	var errs []error
+	for _, st := range []ReceiptStore{s.primary, s.secondary} {
+		if closer, ok := st.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
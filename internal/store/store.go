@@ -0,0 +1,651 @@
+// store.go
+// This file defines the storage abstraction for processed receipts and its
+// in-memory implementations.
+
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+// ReceiptStore persists processed receipts.
+//
+// Stored receipts are treated as immutable snapshots: implementations never mutate
+// a stored value in place. Update swaps in a modified copy under the write lock, so
+// concurrent readers always see a consistent receipt.
+type ReceiptStore interface {
+	// Save stores the receipt under id, assigning it the next insertion sequence number.
+	Save(id string, receipt *models.ProcessedReceipt) error
+	// SaveContext is Save, returning ctx's error without saving once ctx is done. A
+	// save that has started is never abandoned part-way.
+	SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error
+	// Get returns the receipt stored under id and whether it exists.
+	Get(id string) (*models.ProcessedReceipt, bool)
+	// GetContext is Get, returning ctx's error instead once ctx is done.
+	GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error)
+	// GetMany returns the receipts stored under ids, keyed by ID, read as one
+	// consistent snapshot. IDs with no stored receipt are absent from the result.
+	GetMany(ids []string) map[string]*models.ProcessedReceipt
+	// GetManyContext is GetMany, returning ctx's error instead once ctx is done.
+	GetManyContext(ctx context.Context, ids []string) (map[string]*models.ProcessedReceipt, error)
+	// GetByHash returns owner's receipt whose ReceiptHash is hash and whether one
+	// exists. Duplicates are detected per owner, so identical receipts submitted by
+	// different users are stored separately.
+	GetByHash(owner, hash string) (*models.ProcessedReceipt, bool)
+	// GetByDate returns owner's receipts with the given purchase date, in no
+	// particular order, without scanning other receipts.
+	GetByDate(owner, date string) []*models.ProcessedReceipt
+	// Update applies fn to a copy of the stored receipt and atomically replaces it,
+	// returning the new snapshot, or ErrNotFound if no receipt is stored under id.
+	// The store sets no fields itself, so fn updates ModifiedAt from the caller's clock.
+	Update(id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error)
+	// Delete removes the receipt stored under id, returning ErrNotFound if there is none.
+	Delete(id string) error
+	// DeleteContext is Delete, returning ctx's error without deleting once ctx is done.
+	DeleteContext(ctx context.Context, id string) error
+	// Range calls fn for every stored receipt, in no particular order, until fn returns false.
+	Range(fn func(*models.ProcessedReceipt) bool)
+	// RangeContext is Range, stopping early with ctx's error once ctx is done.
+	RangeContext(ctx context.Context, fn func(*models.ProcessedReceipt) bool) error
+	// Ping checks that the store is reachable.
+	Ping() error
+}
+
+// ErrNotFound is returned when no receipt is stored under the requested ID.
+var ErrNotFound = errors.New("receipt not found")
+
+// New returns the store selected by backend: "bolt" opens a durable BoltDB file at
+// path, while "memory" (the default) returns an in-memory store. With more than one
+// shard the in-memory receipts are spread over independently locked shards to
+// reduce lock contention. maxHashes caps the in-memory hash index used for
+// duplicate detection (0 leaves it unbounded); the bolt store ignores it.
+func New(backend, path string, shards, maxHashes int) (ReceiptStore, error) {
+	switch backend {
+	case "", "memory":
+		if shards > 1 {
+			return NewShardedStore(shards, maxHashes), nil
+		}
+		return NewBoundedInMemoryStore(maxHashes), nil
+	case "bolt":
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store backend: %q", backend)
+	}
+}
+
+// InMemoryStore is a ReceiptStore backed by a single map guarded by one RWMutex.
+type InMemoryStore struct {
+	mu        sync.RWMutex                        // Mutex for thread-safe access to receipts map
+	receipts  map[string]*models.ProcessedReceipt // Processed receipts keyed by ID
+	byHash    map[string]*list.Element            // Hash index entries keyed by dedupeKey, guarded by mu
+	byDate    map[string]map[string]struct{}      // IDs of receipts keyed by dateKey, guarded by mu
+	hashes    *list.List                          // Hash index entries, most recently used first, guarded by mu
+	maxHashes int                                 // Maximum number of hash index entries; 0 means unbounded
+	sequence  uint64                              // Last insertion sequence number assigned, guarded by mu
+}
+
+// hashEntry is one hash index entry: the ID of the receipt with a given dedupe key.
+type hashEntry struct {
+	key string
+	id  string
+}
+
+// dedupeKey is the hash index key of owner's receipt with the given ReceiptHash.
+func dedupeKey(owner, hash string) string {
+	return owner + "\x00" + hash
+}
+
+// dateKey is the date index key of owner's receipts with the given purchase date.
+func dateKey(owner, date string) string {
+	return owner + "\x00" + date
+}
+
+// NewInMemoryStore creates an empty InMemoryStore with an unbounded hash index.
+func NewInMemoryStore() *InMemoryStore {
+	return NewBoundedInMemoryStore(0)
+}
+
+// NewBoundedInMemoryStore creates an empty InMemoryStore whose hash index holds at
+// most maxHashes entries, evicting the least recently used one when full. Receipts
+// themselves are never evicted; a receipt whose entry is gone is simply no longer
+// recognized as a duplicate. A maxHashes of 0 leaves the index unbounded.
+func NewBoundedInMemoryStore(maxHashes int) *InMemoryStore {
+	return &InMemoryStore{
+		receipts:  make(map[string]*models.ProcessedReceipt),
+		byHash:    make(map[string]*list.Element),
+		byDate:    make(map[string]map[string]struct{}),
+		hashes:    list.New(),
+		maxHashes: maxHashes,
+	}
+}
+
+// Save stores the receipt under id, assigning it the next insertion sequence number.
+func (s *InMemoryStore) Save(id string, receipt *models.ProcessedReceipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	receipt.Sequence = s.sequence
+	s.put(id, receipt)
+	return nil
+}
+
+// SaveContext stores the receipt like Save unless ctx is already done.
+func (s *InMemoryStore) SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error {
+	return saveContext(ctx, s, id, receipt)
+}
+
+// put stores the receipt under id and indexes it by hash. Callers must hold the write lock.
+func (s *InMemoryStore) put(id string, receipt *models.ProcessedReceipt) {
+	s.receipts[id] = receipt
+	s.index(id, receipt)
+}
+
+// index adds the hash and date index entries for the receipt stored under id.
+// Callers must hold the write lock.
+func (s *InMemoryStore) index(id string, receipt *models.ProcessedReceipt) {
+	s.indexHash(id, receipt)
+	s.indexDate(id, receipt)
+}
+
+// indexHash adds the hash index entry for the receipt stored under id, which need
+// not be held by this store: a ShardedStore keeps each entry in the shard
+// responsible for its key. Callers must hold the write lock.
+func (s *InMemoryStore) indexHash(id string, receipt *models.ProcessedReceipt) {
+	if receipt.ReceiptHash == "" {
+		return
+	}
+	key := dedupeKey(receipt.Owner, receipt.ReceiptHash)
+	if el, exists := s.byHash[key]; exists {
+		el.Value.(*hashEntry).id = id
+		s.hashes.MoveToFront(el)
+		return
+	}
+	s.byHash[key] = s.hashes.PushFront(&hashEntry{key: key, id: id})
+	if s.maxHashes > 0 && s.hashes.Len() > s.maxHashes {
+		oldest := s.hashes.Back()
+		s.hashes.Remove(oldest)
+		delete(s.byHash, oldest.Value.(*hashEntry).key)
+	}
+}
+
+// indexDate adds id to the date index entry of receipt, which like the hash index
+// entry need not be held by this store. Callers must hold the write lock.
+func (s *InMemoryStore) indexDate(id string, receipt *models.ProcessedReceipt) {
+	key := dateKey(receipt.Owner, receipt.Receipt.PurchaseDate)
+	ids, exists := s.byDate[key]
+	if !exists {
+		ids = make(map[string]struct{})
+		s.byDate[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+// unindex drops the index entries for the receipt stored under id. Callers must
+// hold the write lock.
+func (s *InMemoryStore) unindex(id string, receipt *models.ProcessedReceipt) {
+	s.unindexHash(id, receipt)
+	s.unindexDate(id, receipt)
+}
+
+// unindexHash drops the hash index entry for the receipt stored under id, leaving
+// an entry that points at another receipt. Callers must hold the write lock.
+func (s *InMemoryStore) unindexHash(id string, receipt *models.ProcessedReceipt) {
+	key := dedupeKey(receipt.Owner, receipt.ReceiptHash)
+	if el, exists := s.byHash[key]; exists && el.Value.(*hashEntry).id == id {
+		s.hashes.Remove(el)
+		delete(s.byHash, key)
+	}
+}
+
+// unindexDate removes id from the date index entry of receipt. Callers must hold
+// the write lock.
+func (s *InMemoryStore) unindexDate(id string, receipt *models.ProcessedReceipt) {
+	key := dateKey(receipt.Owner, receipt.Receipt.PurchaseDate)
+	delete(s.byDate[key], id)
+	if len(s.byDate[key]) == 0 {
+		delete(s.byDate, key)
+	}
+}
+
+// dateIDs returns the IDs in the date index entry for owner and date. Callers must
+// hold the read lock.
+func (s *InMemoryStore) dateIDs(owner, date string) []string {
+	ids := make([]string, 0, len(s.byDate[dateKey(owner, date)]))
+	for id := range s.byDate[dateKey(owner, date)] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Get returns the receipt stored under id and whether it exists.
+func (s *InMemoryStore) Get(id string) (*models.ProcessedReceipt, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	receipt, exists := s.receipts[id]
+	return receipt, exists
+}
+
+// GetContext returns the receipt stored under id like Get unless ctx is already done.
+func (s *InMemoryStore) GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	return getContext(ctx, s, id)
+}
+
+// GetMany returns the receipts stored under ids under a single read lock.
+func (s *InMemoryStore) GetMany(ids []string) map[string]*models.ProcessedReceipt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := make(map[string]*models.ProcessedReceipt, len(ids))
+	for _, id := range ids {
+		if receipt, exists := s.receipts[id]; exists {
+			found[id] = receipt
+		}
+	}
+	return found
+}
+
+// GetManyContext returns the receipts stored under ids like GetMany unless ctx is already done.
+func (s *InMemoryStore) GetManyContext(ctx context.Context, ids []string) (map[string]*models.ProcessedReceipt, error) {
+	return getManyContext(ctx, s, ids)
+}
+
+// GetByHash returns owner's receipt whose ReceiptHash is hash, using the hash index.
+// A hit marks the entry as recently used, so it takes the write lock.
+func (s *InMemoryStore) GetByHash(owner, hash string) (*models.ProcessedReceipt, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, exists := s.lookupHash(dedupeKey(owner, hash))
+	if !exists {
+		return nil, false
+	}
+	receipt, exists := s.receipts[id]
+	return receipt, exists
+}
+
+// GetByDate returns owner's receipts with the given purchase date, using the date index.
+func (s *InMemoryStore) GetByDate(owner, date string) []*models.ProcessedReceipt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []*models.ProcessedReceipt
+	for _, id := range s.dateIDs(owner, date) {
+		if receipt, exists := s.receipts[id]; exists {
+			found = append(found, receipt)
+		}
+	}
+	return found
+}
+
+// lookupHash returns the receipt ID indexed under key, marking the entry as
+// recently used. Callers must hold the write lock.
+func (s *InMemoryStore) lookupHash(key string) (string, bool) {
+	el, exists := s.byHash[key]
+	if !exists {
+		return "", false
+	}
+	s.hashes.MoveToFront(el)
+	return el.Value.(*hashEntry).id, true
+}
+
+// Update applies fn to a copy of the stored receipt and atomically replaces it.
+func (s *InMemoryStore) Update(id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.receipts[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	next, err := replace(s.receipts, id, fn)
+	if err != nil {
+		return nil, err
+	}
+	if reindex(current, next) {
+		s.unindex(id, current)
+		s.index(id, next)
+	}
+	return next, nil
+}
+
+// Delete removes the receipt stored under id under the write lock.
+func (s *InMemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	receipt, exists := s.receipts[id]
+	if !exists {
+		return ErrNotFound
+	}
+	s.unindex(id, receipt)
+	delete(s.receipts, id)
+	return nil
+}
+
+// DeleteContext removes the receipt stored under id like Delete unless ctx is already done.
+func (s *InMemoryStore) DeleteContext(ctx context.Context, id string) error {
+	return deleteContext(ctx, s, id)
+}
+
+// Range calls fn for every stored receipt until fn returns false. The read lock is
+// held for the duration, so fn must not call back into the store.
+func (s *InMemoryStore) Range(fn func(*models.ProcessedReceipt) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, receipt := range s.receipts {
+		if !fn(receipt) {
+			return
+		}
+	}
+}
+
+// RangeContext calls fn like Range, checking ctx before each receipt.
+func (s *InMemoryStore) RangeContext(ctx context.Context, fn func(*models.ProcessedReceipt) bool) error {
+	return rangeContext(ctx, s, fn)
+}
+
+// Ping checks that the store is reachable. For the in-memory store this only waits
+// for the read lock, so its latency reflects lock contention.
+func (s *InMemoryStore) Ping() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return nil
+}
+
+// ShardedStore is a ReceiptStore that spreads receipts over several InMemoryStore
+// shards keyed by a hash of the receipt ID, so that operations on different
+// receipts rarely contend for the same lock. The hash index is sharded the same
+// way but by its own key, so a duplicate lookup touches one shard's index and the
+// shard holding the receipt it finds.
+type ShardedStore struct {
+	shards   []*InMemoryStore
+	sequence atomic.Uint64 // Last insertion sequence number assigned across all shards
+}
+
+// NewShardedStore creates an empty ShardedStore with n shards. A positive maxHashes
+// is split evenly between the shards' hash indexes, rounding up.
+func NewShardedStore(n, maxHashes int) *ShardedStore {
+	perShard := 0
+	if maxHashes > 0 {
+		perShard = (maxHashes + n - 1) / n
+	}
+	s := &ShardedStore{shards: make([]*InMemoryStore, n)}
+	for i := range s.shards {
+		s.shards[i] = NewBoundedInMemoryStore(perShard)
+	}
+	return s
+}
+
+// shard returns the shard responsible for key: a receipt ID, or an index key.
+func (s *ShardedStore) shard(key string) *InMemoryStore {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Save stores the receipt under id, assigning it the next global insertion sequence number.
+func (s *ShardedStore) Save(id string, receipt *models.ProcessedReceipt) error {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	receipt.Sequence = s.sequence.Add(1)
+	shard.receipts[id] = receipt
+	shard.mu.Unlock()
+
+	s.index(id, receipt)
+	return nil
+}
+
+// index adds the index entries of the receipt stored under id to the shards
+// responsible for their keys.
+func (s *ShardedStore) index(id string, receipt *models.ProcessedReceipt) {
+	if receipt.ReceiptHash != "" {
+		shard := s.shard(dedupeKey(receipt.Owner, receipt.ReceiptHash))
+		shard.mu.Lock()
+		shard.indexHash(id, receipt)
+		shard.mu.Unlock()
+	}
+
+	shard := s.shard(dateKey(receipt.Owner, receipt.Receipt.PurchaseDate))
+	shard.mu.Lock()
+	shard.indexDate(id, receipt)
+	shard.mu.Unlock()
+}
+
+// unindex removes the index entries of the receipt stored under id.
+func (s *ShardedStore) unindex(id string, receipt *models.ProcessedReceipt) {
+	shard := s.shard(dedupeKey(receipt.Owner, receipt.ReceiptHash))
+	shard.mu.Lock()
+	shard.unindexHash(id, receipt)
+	shard.mu.Unlock()
+
+	shard = s.shard(dateKey(receipt.Owner, receipt.Receipt.PurchaseDate))
+	shard.mu.Lock()
+	shard.unindexDate(id, receipt)
+	shard.mu.Unlock()
+}
+
+// SaveContext stores the receipt like Save unless ctx is already done.
+func (s *ShardedStore) SaveContext(ctx context.Context, id string, receipt *models.ProcessedReceipt) error {
+	return saveContext(ctx, s, id, receipt)
+}
+
+// Get returns the receipt stored under id and whether it exists.
+func (s *ShardedStore) Get(id string) (*models.ProcessedReceipt, bool) {
+	return s.shard(id).Get(id)
+}
+
+// GetContext returns the receipt stored under id like Get unless ctx is already done.
+func (s *ShardedStore) GetContext(ctx context.Context, id string) (*models.ProcessedReceipt, bool, error) {
+	return getContext(ctx, s, id)
+}
+
+// GetMany returns the receipts stored under ids. The read locks of all shards are
+// held together, in shard order, so the result is one consistent snapshot.
+func (s *ShardedStore) GetMany(ids []string) map[string]*models.ProcessedReceipt {
+	for _, shard := range s.shards {
+		shard.mu.RLock()
+		defer shard.mu.RUnlock()
+	}
+
+	found := make(map[string]*models.ProcessedReceipt, len(ids))
+	for _, id := range ids {
+		if receipt, exists := s.shard(id).receipts[id]; exists {
+			found[id] = receipt
+		}
+	}
+	return found
+}
+
+// GetManyContext returns the receipts stored under ids like GetMany unless ctx is already done.
+func (s *ShardedStore) GetManyContext(ctx context.Context, ids []string) (map[string]*models.ProcessedReceipt, error) {
+	return getManyContext(ctx, s, ids)
+}
+
+// GetByHash returns owner's receipt whose ReceiptHash is hash, consulting only the
+// shard responsible for the hash index key.
+func (s *ShardedStore) GetByHash(owner, hash string) (*models.ProcessedReceipt, bool) {
+	shard := s.shard(dedupeKey(owner, hash))
+	shard.mu.Lock()
+	id, exists := shard.lookupHash(dedupeKey(owner, hash))
+	shard.mu.Unlock()
+	if !exists {
+		return nil, false
+	}
+	return s.Get(id)
+}
+
+// GetByDate returns owner's receipts with the given purchase date, consulting only
+// the shard responsible for the date index key and the shards holding the receipts.
+func (s *ShardedStore) GetByDate(owner, date string) []*models.ProcessedReceipt {
+	shard := s.shard(dateKey(owner, date))
+	shard.mu.RLock()
+	ids := shard.dateIDs(owner, date)
+	shard.mu.RUnlock()
+
+	var found []*models.ProcessedReceipt
+	for _, id := range ids {
+		if receipt, exists := s.Get(id); exists {
+			found = append(found, receipt)
+		}
+	}
+	return found
+}
+
+// Update applies fn to a copy of the stored receipt and atomically replaces it,
+// moving its index entries when the keys they are filed under change.
+func (s *ShardedStore) Update(id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error) {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	current, exists := shard.receipts[id]
+	if !exists {
+		shard.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	next, err := replace(shard.receipts, id, fn)
+	shard.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if reindex(current, next) {
+		s.unindex(id, current)
+		s.index(id, next)
+	}
+	return next, nil
+}
+
+// Delete removes the receipt stored under id and its index entries.
+func (s *ShardedStore) Delete(id string) error {
+	shard := s.shard(id)
+	shard.mu.Lock()
+	receipt, exists := shard.receipts[id]
+	delete(shard.receipts, id)
+	shard.mu.Unlock()
+	if !exists {
+		return ErrNotFound
+	}
+
+	s.unindex(id, receipt)
+	return nil
+}
+
+// DeleteContext removes the receipt stored under id like Delete unless ctx is already done.
+func (s *ShardedStore) DeleteContext(ctx context.Context, id string) error {
+	return deleteContext(ctx, s, id)
+}
+
+// Range calls fn for every stored receipt until fn returns false, visiting one shard at a time.
+func (s *ShardedStore) Range(fn func(*models.ProcessedReceipt) bool) {
+	for _, shard := range s.shards {
+		stop := false
+		shard.Range(func(receipt *models.ProcessedReceipt) bool {
+			if !fn(receipt) {
+				stop = true
+				return false
+			}
+			return true
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+// RangeContext calls fn like Range, checking ctx before each receipt.
+func (s *ShardedStore) RangeContext(ctx context.Context, fn func(*models.ProcessedReceipt) bool) error {
+	return rangeContext(ctx, s, fn)
+}
+
+// Ping checks that every shard is reachable.
+func (s *ShardedStore) Ping() error {
+	for _, shard := range s.shards {
+		if err := shard.Ping(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replace performs the copy-on-write update of the receipt stored under id.
+// Callers must hold the write lock guarding receipts.
+func replace(receipts map[string]*models.ProcessedReceipt, id string, fn func(*models.ProcessedReceipt)) (*models.ProcessedReceipt, error) {
+	current, exists := receipts[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// Copy-on-write: readers holding the old pointer keep a consistent view
+	next := *current
+	fn(&next)
+	receipts[id] = &next
+	return &next, nil
+}
+
+// reindex reports whether an update from current to next changes the keys the
+// receipt is indexed under.
+func reindex(current, next *models.ProcessedReceipt) bool {
+	return next.ReceiptHash != current.ReceiptHash || next.Owner != current.Owner ||
+		next.Receipt.PurchaseDate != current.Receipt.PurchaseDate
+}
+
+// saveContext implements SaveContext on top of s.Save. The check comes first so
+// that a cancelled request never leaves a receipt half stored.
+func saveContext(ctx context.Context, s ReceiptStore, id string, receipt *models.ProcessedReceipt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Save(id, receipt)
+}
+
+// getContext implements GetContext on top of s.Get.
+func getContext(ctx context.Context, s ReceiptStore, id string) (*models.ProcessedReceipt, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	receipt, exists := s.Get(id)
+	return receipt, exists, nil
+}
+
+// getManyContext implements GetManyContext on top of s.GetMany.
+func getManyContext(ctx context.Context, s ReceiptStore, ids []string) (map[string]*models.ProcessedReceipt, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.GetMany(ids), nil
+}
+
+// deleteContext implements DeleteContext on top of s.Delete. Like saveContext it
+// checks first, so a cancelled request never leaves a delete half done.
+func deleteContext(ctx context.Context, s ReceiptStore, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Delete(id)
+}
+
+// rangeContext implements RangeContext on top of s.Range, ending the iteration at
+// the first receipt reached after ctx is done.
+func rangeContext(ctx context.Context, s ReceiptStore, fn func(*models.ProcessedReceipt) bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var err error
+	s.Range(func(receipt *models.ProcessedReceipt) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		return fn(receipt)
+	})
+	return err
+}
@@ -0,0 +1,420 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/saurabhag23/receipt-processor/internal/models"
+)
+
+// forEachStore runs fn against an empty store of every implementation.
+func forEachStore(t *testing.T, fn func(t *testing.T, s ReceiptStore)) {
+	t.Run("memory", func(t *testing.T) { fn(t, NewInMemoryStore()) })
+	t.Run("sharded", func(t *testing.T) { fn(t, NewShardedStore(4, 0)) })
+	t.Run("bolt", func(t *testing.T) {
+		s, err := NewBoltStore(filepath.Join(t.TempDir(), "receipts.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		fn(t, s)
+	})
+}
+
+// newReceipt returns a receipt owned by owner with the given hash and purchase date.
+func newReceipt(id, owner, hash, date string) *models.ProcessedReceipt {
+	return &models.ProcessedReceipt{
+		ID:          id,
+		Owner:       owner,
+		ReceiptHash: hash,
+		Receipt:     models.Receipt{Retailer: "Target", PurchaseDate: date, PurchaseTime: "13:01"},
+	}
+}
+
+func TestGetByHashIsScopedToTheOwner(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		for _, r := range []*models.ProcessedReceipt{
+			newReceipt("a", "alice", "h1", "2022-01-01"),
+			newReceipt("b", "bob", "h1", "2022-01-01"),
+		} {
+			if err := s.Save(r.ID, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for owner, want := range map[string]string{"alice": "a", "bob": "b"} {
+			got, ok := s.GetByHash(owner, "h1")
+			if !ok || got.ID != want {
+				t.Errorf("GetByHash(%q) = %v, %v; want receipt %s", owner, got, ok, want)
+			}
+		}
+		if got, ok := s.GetByHash("carol", "h1"); ok {
+			t.Errorf("GetByHash(carol) = %s, want no receipt", got.ID)
+		}
+
+		// Deleting one owner's receipt leaves the other's indexed
+		if err := s.Delete("a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := s.GetByHash("alice", "h1"); ok {
+			t.Error("alice's deleted receipt is still indexed")
+		}
+		if got, ok := s.GetByHash("bob", "h1"); !ok || got.ID != "b" {
+			t.Errorf("GetByHash(bob) after deleting alice's receipt = %v, %v", got, ok)
+		}
+	})
+}
+
+func TestGetByHashFollowsUpdates(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		// Enough receipts that their IDs and hash keys land on different shards
+		for i := 0; i < 16; i++ {
+			r := newReceipt(fmt.Sprintf("r%d", i), "alice", fmt.Sprintf("h%d", i), "2022-01-01")
+			if err := s.Save(r.ID, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i := 0; i < 16; i++ {
+			if got, ok := s.GetByHash("alice", fmt.Sprintf("h%d", i)); !ok || got.ID != fmt.Sprintf("r%d", i) {
+				t.Fatalf("GetByHash(h%d) = %v, %v", i, got, ok)
+			}
+		}
+
+		if _, err := s.Update("r3", func(r *models.ProcessedReceipt) { r.ReceiptHash = "rehashed" }); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := s.GetByHash("alice", "h3"); ok {
+			t.Error("old hash still indexed after update")
+		}
+		if got, ok := s.GetByHash("alice", "rehashed"); !ok || got.ID != "r3" {
+			t.Errorf("GetByHash(rehashed) = %v, %v; want r3", got, ok)
+		}
+	})
+}
+
+// BenchmarkStoreParallel mixes saves, reads and duplicate lookups from concurrent
+// goroutines, roughly as the process and points endpoints do.
+func BenchmarkStoreParallel(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		new  func() ReceiptStore
+	}{
+		{"memory", func() ReceiptStore { return NewInMemoryStore() }},
+		{"sharded", func() ReceiptStore { return NewShardedStore(16, 0) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s := bc.new()
+			var next atomic.Int64
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					id := fmt.Sprintf("r%d", n)
+					owner := fmt.Sprintf("user%d", n%32)
+					if err := s.Save(id, newReceipt(id, owner, id, "2022-01-01")); err != nil {
+						b.Fatal(err)
+					}
+					s.Get(fmt.Sprintf("r%d", n/2+1))
+					s.GetByHash(owner, id)
+				}
+			})
+		})
+	}
+}
+
+func TestGetByDate(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		for _, r := range []*models.ProcessedReceipt{
+			newReceipt("a1", "alice", "h1", "2022-01-01"),
+			newReceipt("a2", "alice", "h2", "2022-01-01"),
+			newReceipt("a3", "alice", "h3", "2022-01-02"),
+			newReceipt("b1", "bob", "h1", "2022-01-01"),
+		} {
+			if err := s.Save(r.ID, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ids := func(owner, date string) string {
+			var ids []string
+			for _, r := range s.GetByDate(owner, date) {
+				ids = append(ids, r.ID)
+			}
+			sort.Strings(ids)
+			return strings.Join(ids, ",")
+		}
+		for _, tc := range []struct{ owner, date, want string }{
+			{"alice", "2022-01-01", "a1,a2"},
+			{"alice", "2022-01-02", "a3"},
+			{"bob", "2022-01-01", "b1"},
+			{"bob", "2022-01-02", ""},
+		} {
+			if got := ids(tc.owner, tc.date); got != tc.want {
+				t.Errorf("GetByDate(%s, %s) = %q, want %q", tc.owner, tc.date, got, tc.want)
+			}
+		}
+
+		// Changing the purchase date moves the receipt, and deleting it drops it
+		if _, err := s.Update("a2", func(r *models.ProcessedReceipt) { r.Receipt.PurchaseDate = "2022-01-02" }); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Delete("a3"); err != nil {
+			t.Fatal(err)
+		}
+		if got := ids("alice", "2022-01-01"); got != "a1" {
+			t.Errorf("GetByDate(alice, 2022-01-01) after update = %q, want a1", got)
+		}
+		if got := ids("alice", "2022-01-02"); got != "a2" {
+			t.Errorf("GetByDate(alice, 2022-01-02) after update and delete = %q, want a2", got)
+		}
+	})
+}
+
+func TestContextMethodsStopOnceDone(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		r := newReceipt("a", "alice", "h1", "2022-01-01")
+		if err := s.Save(r.ID, r); err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if found, err := s.GetManyContext(ctx, []string{"a"}); !errors.Is(err, context.Canceled) || found != nil {
+			t.Errorf("GetManyContext = %v, %v; want context.Canceled", found, err)
+		}
+		if err := s.DeleteContext(ctx, "a"); !errors.Is(err, context.Canceled) {
+			t.Errorf("DeleteContext = %v, want context.Canceled", err)
+		}
+		if _, ok := s.Get("a"); !ok {
+			t.Error("cancelled DeleteContext removed the receipt")
+		}
+
+		found, err := s.GetManyContext(context.Background(), []string{"a", "missing"})
+		if err != nil || len(found) != 1 || found["a"] == nil {
+			t.Errorf("GetManyContext = %v, %v; want receipt a only", found, err)
+		}
+		if err := s.DeleteContext(context.Background(), "a"); err != nil {
+			t.Errorf("DeleteContext = %v", err)
+		}
+	})
+}
+
+func TestConcurrentUpdatesOfOneReceipt(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		r := newReceipt("a", "alice", "h1", "2022-01-01")
+		if err := s.Save(r.ID, r); err != nil {
+			t.Fatal(err)
+		}
+
+		// Writers bump the points and breakdown together; readers check that they
+		// never see one without the other
+		const writers, updates = 8, 25
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < updates; j++ {
+					if _, err := s.Update("a", func(r *models.ProcessedReceipt) {
+						r.Points++
+						r.Breakdown = append(r.Breakdown[:len(r.Breakdown):len(r.Breakdown)], models.RuleResult{Points: 1})
+					}); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+		}
+		done := make(chan struct{})
+		var readers sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if got, ok := s.Get("a"); !ok || got.Points != len(got.Breakdown) {
+						t.Errorf("read a torn receipt: %v, %v", got, ok)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(done)
+		readers.Wait()
+
+		if got, _ := s.Get("a"); got.Points != writers*updates {
+			t.Errorf("points = %d after %d updates, want no lost updates", got.Points, writers*updates)
+		}
+	})
+}
+
+func TestDualStoreWritesBothAndFallsBack(t *testing.T) {
+	for _, fallback := range []bool{true, false} {
+		t.Run(fmt.Sprintf("fallback=%v", fallback), func(t *testing.T) {
+			primary, secondary := NewInMemoryStore(), NewInMemoryStore()
+			s := NewDualStore(primary, secondary, fallback)
+
+			// Writes through the dual store reach both stores
+			if err := s.Save("a", newReceipt("a", "alice", "h1", "2022-01-01")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Update("a", func(r *models.ProcessedReceipt) { r.Points = 28 }); err != nil {
+				t.Fatal(err)
+			}
+			for name, st := range map[string]ReceiptStore{"primary": primary, "secondary": secondary} {
+				if got, ok := st.Get("a"); !ok || got.Points != 28 {
+					t.Errorf("%s after save and update = %v, %v; want 28 points", name, got, ok)
+				}
+			}
+
+			// A receipt only the secondary holds is read from it when fallback is on
+			old := newReceipt("old", "alice", "h2", "2022-01-01")
+			if err := secondary.Save(old.ID, old); err != nil {
+				t.Fatal(err)
+			}
+			want := 1 // Receipts found for a lookup of both
+			if fallback {
+				want = 2
+			}
+			if _, ok := s.Get("old"); ok != fallback {
+				t.Errorf("Get(old) found = %v, want %v", ok, fallback)
+			}
+			if got := s.GetMany([]string{"a", "old"}); len(got) != want {
+				t.Errorf("GetMany = %d receipts, want %d", len(got), want)
+			}
+			if _, ok := s.GetByHash("alice", "h2"); ok != fallback {
+				t.Errorf("GetByHash(h2) found = %v, want %v", ok, fallback)
+			}
+			if got := s.GetByDate("alice", "2022-01-01"); len(got) != want {
+				t.Errorf("GetByDate = %d receipts, want %d", len(got), want)
+			}
+
+			if err := s.Delete("a"); err != nil {
+				t.Fatal(err)
+			}
+			for name, st := range map[string]ReceiptStore{"primary": primary, "secondary": secondary} {
+				if _, ok := st.Get("a"); ok {
+					t.Errorf("%s still holds the deleted receipt", name)
+				}
+			}
+		})
+	}
+}
+
+func TestDualStoreUpdateAppliesFnOnce(t *testing.T) {
+	primary, secondary := NewInMemoryStore(), NewInMemoryStore()
+	s := NewDualStore(primary, secondary, true)
+	if err := s.Save("a", newReceipt("a", "alice", "h1", "2022-01-01")); err != nil {
+		t.Fatal(err)
+	}
+	// The stores disagree, as they may mid-migration
+	secondary.Update("a", func(r *models.ProcessedReceipt) { r.Points = 99 })
+	secondarySequence := func() uint64 { r, _ := secondary.Get("a"); return r.Sequence }()
+
+	calls, oldPoints := 0, -1
+	updated, err := s.Update("a", func(r *models.ProcessedReceipt) {
+		calls++
+		oldPoints = r.Points
+		r.Points += 10
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || oldPoints != 0 || updated.Points != 10 {
+		t.Errorf("fn ran %d times and saw %d points, returning %d; want once, 0 and 10", calls, oldPoints, updated.Points)
+	}
+	if got, _ := secondary.Get("a"); got.Points != 10 || got.Sequence != secondarySequence {
+		t.Errorf("secondary = %d points, sequence %d; want the primary's 10 points and its own sequence %d", got.Points, got.Sequence, secondarySequence)
+	}
+
+	// A receipt missing from the secondary is written there by the update
+	if err := primary.Save("b", newReceipt("b", "alice", "h2", "2022-01-01")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update("b", func(r *models.ProcessedReceipt) { r.Points = 5 }); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := secondary.Get("b"); !ok || got.Points != 5 {
+		t.Errorf("secondary after updating a receipt it lacked = %v, %v; want 5 points", got, ok)
+	}
+}
+
+func TestBoundedHashIndexEvictsTheLeastRecentlyUsed(t *testing.T) {
+	const maxHashes = 3
+	s := NewBoundedInMemoryStore(maxHashes)
+	save := func(i int) {
+		t.Helper()
+		r := newReceipt(fmt.Sprintf("r%d", i), "alice", fmt.Sprintf("h%d", i), "2022-01-01")
+		if err := s.Save(r.ID, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < maxHashes; i++ {
+		save(i)
+	}
+
+	// One past the cap evicts the oldest entry, which no longer dedupes
+	save(maxHashes)
+	if got, ok := s.GetByHash("alice", "h0"); ok {
+		t.Errorf("GetByHash(h0) = %s after filling past the cap, want it evicted", got.ID)
+	}
+	for i := 1; i <= maxHashes; i++ {
+		if _, ok := s.GetByHash("alice", fmt.Sprintf("h%d", i)); !ok {
+			t.Errorf("GetByHash(h%d) missing, want it kept", i)
+		}
+	}
+	// The evicted receipt itself is still stored
+	if _, ok := s.Get("r0"); !ok {
+		t.Error("evicting the hash entry removed receipt r0")
+	}
+
+	// A lookup counts as a use, so the entry looked up survives the next eviction
+	s.GetByHash("alice", "h1")
+	save(maxHashes + 1)
+	if _, ok := s.GetByHash("alice", "h1"); !ok {
+		t.Error("recently used h1 was evicted")
+	}
+	if _, ok := s.GetByHash("alice", "h2"); ok {
+		t.Error("least recently used h2 was kept")
+	}
+}
+
+func TestUpdateLeavesModifiedAtToTheCaller(t *testing.T) {
+	forEachStore(t, func(t *testing.T, s ReceiptStore) {
+		saved := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+		r := newReceipt("a", "alice", "h1", "2022-01-01")
+		r.ModifiedAt = saved
+		if err := s.Save(r.ID, r); err != nil {
+			t.Fatal(err)
+		}
+
+		updated, err := s.Update("a", func(r *models.ProcessedReceipt) { r.Points = 1 })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.ModifiedAt.Equal(saved) {
+			t.Errorf("modifiedAt = %s after an update that left it alone, want %s", updated.ModifiedAt, saved)
+		}
+
+		modified := saved.Add(time.Hour)
+		if updated, err = s.Update("a", func(r *models.ProcessedReceipt) { r.ModifiedAt = modified }); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := s.Get("a"); !updated.ModifiedAt.Equal(modified) || !got.ModifiedAt.Equal(modified) {
+			t.Errorf("modifiedAt = %s, stored %s; want %s", updated.ModifiedAt, got.ModifiedAt, modified)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+// clock.go
+package utils
+
+import "time"
+
+// Clock tells the current time. Code that depends on the time of processing takes a
+// Clock so that callers can substitute a fixed time.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the Clock backed by the system time
+type SystemClock struct{}
+
+// Now returns the current system time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,53 @@
+// currency.go
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// currencyCodes maps ISO-4217 alphabetic currency codes to their numeric codes
+var currencyCodes = map[string]string{
+	"AED": "784", "ARS": "032", "AUD": "036", "BRL": "986", "CAD": "124",
+	"CHF": "756", "CLP": "152", "CNY": "156", "COP": "170", "CZK": "203",
+	"DKK": "208", "EGP": "818", "EUR": "978", "GBP": "826", "HKD": "344",
+	"HUF": "348", "IDR": "360", "ILS": "376", "INR": "356", "JPY": "392",
+	"KRW": "410", "MXN": "484", "MYR": "458", "NGN": "566", "NOK": "578",
+	"NZD": "554", "PHP": "608", "PKR": "586", "PLN": "985", "RON": "946",
+	"SAR": "682", "SEK": "752", "SGD": "702", "THB": "764", "TRY": "949",
+	"TWD": "901", "UAH": "980", "USD": "840", "VND": "704", "ZAR": "710",
+}
+
+// numericCurrencyCodes is the reverse of currencyCodes, mapping numeric codes to alphabetic ones
+var numericCurrencyCodes = func() map[string]string {
+	m := make(map[string]string, len(currencyCodes))
+	for alpha, numeric := range currencyCodes {
+		m[numeric] = alpha
+	}
+	return m
+}()
+
+// NormalizeCurrency validates an ISO-4217 currency code given in either its alphabetic
+// ("usd", "USD") or numeric ("840") form and returns the uppercase alphabetic code
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := currencyCodes[code]; ok {
+		return code, nil
+	}
+	if alpha, ok := numericCurrencyCodes[code]; ok {
+		return alpha, nil
+	}
+	return "", fmt.Errorf("unknown currency code: %q", code)
+}
+
+// CurrencyCodes returns every code NormalizeCurrency accepts, alphabetic and
+// numeric, in sorted order
+func CurrencyCodes() []string {
+	codes := make([]string, 0, 2*len(currencyCodes))
+	for alpha, numeric := range currencyCodes {
+		codes = append(codes, alpha, numeric)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestNormalizeCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"USD", "USD", true},
+		{"usd", "USD", true},
+		{" eur ", "EUR", true},
+		{"840", "USD", true},
+		{"032", "ARS", true},
+		{"32", "", false},
+		{"XYZ", "", false},
+		{"999", "", false},
+		{"", "", false},
+	} {
+		got, err := NormalizeCurrency(tc.code)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v; want %q, ok %v", tc.code, got, err, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// money.go
+package utils
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Policies for rounding amounts that lie exactly on a half cent
+const (
+	HalfCentReject = "reject" // Refuse the amount as ambiguous
+	HalfCentUp     = "up"     // Round away from zero, so 35.345 becomes 35.35
+	HalfCentEven   = "even"   // Round to the even cent, so 35.345 becomes 35.34
+)
+
+// ParseCents converts an amount formatted as "0.00", or "-0.00" for discounts, into
+// an exact number of cents, avoiding the rounding errors of floating-point parsing.
+// A leading '-' is the only sign accepted; "+1.00" is rejected.
+func ParseCents(amount string) (int64, error) {
+	unsigned, negative := strings.CutPrefix(amount, "-")
+	whole, frac, found := strings.Cut(unsigned, ".")
+	if !found || !isDigits(whole) || len(frac) != 2 || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount format: %q", amount)
+	}
+	dollars, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount format: %q", amount)
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	if negative {
+		return -(dollars*100 + cents), nil
+	}
+	return dollars*100 + cents, nil
+}
+
+// isDigits reports whether s is a non-empty run of ASCII digits. strconv.ParseInt
+// alone would also accept a sign.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundCents converts a decimal amount with any number of fractional digits, such as
+// "35.349999", into cents rounded to the nearest cent. It works on the decimal digits
+// rather than a float64, so the result is exact and deterministic: "35.344" is 3534
+// and "35.349999" is 3535. Amounts exactly on a half cent, such as "35.345", follow
+// halfCent, one of the HalfCent policies. Exponent notation is not accepted.
+func RoundCents(amount string, halfCent string) (int64, error) {
+	if strings.ContainsAny(amount, "eE/") {
+		return 0, fmt.Errorf("invalid amount format: %q", amount)
+	}
+	value, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount format: %q", amount)
+	}
+	value.Mul(value, big.NewRat(100, 1))
+
+	// Split |value| into whole cents and a remainder, then round on the remainder
+	den := value.Denom()
+	cents, rem := new(big.Int).QuoRem(new(big.Int).Abs(value.Num()), den, new(big.Int))
+	switch rem.Lsh(rem, 1).Cmp(den) {
+	case 1:
+		cents.Add(cents, big.NewInt(1))
+	case 0:
+		switch halfCent {
+		case HalfCentUp:
+			cents.Add(cents, big.NewInt(1))
+		case HalfCentEven:
+			if cents.Bit(0) == 1 {
+				cents.Add(cents, big.NewInt(1))
+			}
+		default:
+			return 0, fmt.Errorf("amount %s is exactly on a half cent and cannot be rounded unambiguously", amount)
+		}
+	}
+	if !cents.IsInt64() {
+		return 0, fmt.Errorf("amount out of range: %q", amount)
+	}
+	if value.Sign() < 0 {
+		return -cents.Int64(), nil
+	}
+	return cents.Int64(), nil
+}
+
+// FormatCents formats a number of cents as an amount string such as "12.34".
+func FormatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
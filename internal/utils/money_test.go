@@ -0,0 +1,88 @@
+package utils
+
+import "testing"
+
+func TestParseCents(t *testing.T) {
+	for _, tc := range []struct {
+		amount string
+		want   int64
+		ok     bool
+	}{
+		{"0.00", 0, true},
+		{"0.10", 10, true},
+		{"1.15", 115, true},
+		{"4.35", 435, true},
+		{"99.99", 9999, true},
+		{"35.35", 3535, true},
+		{"-1.15", -115, true},
+		{"007.05", 705, true},
+		{"+1.15", 0, false},
+		{"-+1.15", 0, false},
+		{"--1.15", 0, false},
+		{"1.+5", 0, false},
+		{"1.-5", 0, false},
+		{" 1.15", 0, false},
+		{"1.1", 0, false},
+		{"1.150", 0, false},
+		{".15", 0, false},
+		{"1", 0, false},
+		{"", 0, false},
+		{"1,15", 0, false},
+		{"99999999999999999999.00", 0, false},
+	} {
+		got, err := ParseCents(tc.amount)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("ParseCents(%q) = %d, %v; want %d, ok %v", tc.amount, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	for cents, want := range map[int64]string{
+		0:     "0.00",
+		10:    "0.10",
+		115:   "1.15",
+		435:   "4.35",
+		9999:  "99.99",
+		-5:    "-0.05",
+		-1234: "-12.34",
+	} {
+		if got := FormatCents(cents); got != want {
+			t.Errorf("FormatCents(%d) = %q, want %q", cents, got, want)
+		}
+		if round, err := ParseCents(want); err != nil || round != cents {
+			t.Errorf("ParseCents(FormatCents(%d)) = %d, %v", cents, round, err)
+		}
+	}
+}
+
+func TestRoundCents(t *testing.T) {
+	for _, tc := range []struct {
+		amount, halfCent string
+		want             int64
+		ok               bool
+	}{
+		// Amounts already in cents, which float64 cannot represent exactly
+		{"0.10", HalfCentReject, 10, true},
+		{"1.15", HalfCentReject, 115, true},
+		{"4.35", HalfCentReject, 435, true},
+		{"99.99", HalfCentReject, 9999, true},
+		{"35.344", HalfCentReject, 3534, true},
+		{"35.349999", HalfCentReject, 3535, true},
+		{"-35.349999", HalfCentReject, -3535, true},
+		{"12", HalfCentReject, 1200, true},
+		{"35.345", HalfCentUp, 3535, true},
+		{"35.345", HalfCentEven, 3534, true},
+		{"35.355", HalfCentEven, 3536, true},
+		{"-35.345", HalfCentUp, -3535, true},
+		{"35.345", HalfCentReject, 0, false},
+		{"3.5e1", HalfCentReject, 0, false},
+		{"1/3", HalfCentReject, 0, false},
+		{"abc", HalfCentReject, 0, false},
+	} {
+		got, err := RoundCents(tc.amount, tc.halfCent)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("RoundCents(%q, %s) = %d, %v; want %d, ok %v", tc.amount, tc.halfCent, got, err, tc.want, tc.ok)
+		}
+	}
+}
@@ -10,44 +10,152 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// Define a secret key for JWT signing (in a real application, this should be stored securely)
+// jwtSecret is the key used to sign and verify JWTs. The built-in value is for local
+// development only; deployments supply their own with SetJWTSecret.
 var jwtSecret = []byte("your_secret_key")
 
-// GenerateJWT generates a new JWT token with a 1-hour expiration for a specific user
+// tokenTTL is the lifetime of tokens issued by GenerateJWT and GenerateJWTWithRole
+var tokenTTL = 1 * time.Hour
+
+// SetJWTSecret replaces the JWT signing secret; it should be called once at startup
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
+
+// SetTokenTTL replaces the default token lifetime; it should be called once at startup
+func SetTokenTTL(ttl time.Duration) {
+	tokenTTL = ttl
+}
+
+// TokenTTL returns the default token lifetime
+func TokenTTL() time.Duration {
+	return tokenTTL
+}
+
+// authSchemes lists the accepted Authorization schemes, matched case-insensitively
+var authSchemes = []string{"Bearer"}
+
+// SetAuthSchemes replaces the accepted Authorization schemes; it should be called once at startup
+func SetAuthSchemes(schemes []string) {
+	authSchemes = schemes
+}
+
+// Roles that can be embedded in a JWT
+const (
+	RoleUser  = "user"  // Regular API client
+	RoleAdmin = "admin" // Operator allowed to use administrative endpoints
+)
+
+// Claims are the JWT claims issued by this service: the registered claims plus the caller's role and tenant
+type Claims struct {
+	Role   string `json:"role,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateJWT generates a new JWT token with the default lifetime for a specific user
 func GenerateJWT(username string) (string, error) {
+	return GenerateJWTWithRole(username, RoleUser)
+}
+
+// GenerateJWTWithRole generates a new JWT token with the default lifetime for a user with the given role
+func GenerateJWTWithRole(username, role string) (string, error) {
+	return GenerateJWTWithTTL(username, role, tokenTTL)
+}
+
+// GenerateJWTWithTTL generates a new JWT token for a user with the given role that expires after ttl
+func GenerateJWTWithTTL(username, role string, ttl time.Duration) (string, error) {
+	token, _, err := IssueJWT(username, role, ttl)
+	return token, err
+}
+
+// IssueJWT generates a new JWT token for a user with the given role that expires after ttl,
+// returning the signed token together with its expiration time
+func IssueJWT(username, role string, ttl time.Duration) (string, time.Time, error) {
+	return IssueTenantJWT(username, role, "", ttl)
+}
+
+// IssueTenantJWT is IssueJWT for a user belonging to tenant; an empty tenant is omitted from the token
+func IssueTenantJWT(username, role, tenant string, ttl time.Duration) (string, time.Time, error) {
 	// Define token expiration time
-	expirationTime := time.Now().Add(1 * time.Hour)
+	expirationTime := time.Now().Add(ttl)
 
-	// Create claims, including username and expiration time
-	claims := &jwt.RegisteredClaims{
-		Subject:   username,
-		ExpiresAt: jwt.NewNumericDate(expirationTime),
+	// Create claims, including username, role, tenant and expiration time
+	claims := &Claims{
+		Role:   role,
+		Tenant: tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   username,
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		},
 	}
 
 	// Create token with claims and sign it using the secret key
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expirationTime, nil
 }
 
 // ValidateJWT validates the JWT token in the request header
 func ValidateJWT(r *http.Request) bool {
-	// Get the token from the Authorization header
-	tokenString := r.Header.Get("Authorization")
-	if tokenString == "" {
-		return false
+	_, err := ParseJWT(r)
+	return err == nil
+}
+
+// ParseJWT validates the JWT token in the request header and returns its claims,
+// so callers can identify the user who made the request
+func ParseJWT(r *http.Request) (*Claims, error) {
+	// Get the token from the Authorization header, refusing ambiguous requests with several
+	headers := r.Header.Values("Authorization")
+	if len(headers) > 1 {
+		return nil, fmt.Errorf("multiple authorization headers")
+	}
+	if len(headers) == 0 || headers[0] == "" {
+		return nil, fmt.Errorf("missing authorization header")
 	}
 
-	// Remove the "Bearer " prefix if present
-	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
+	// Remove the scheme prefix if present
+	tokenString, err := stripAuthScheme(headers[0])
+	if err != nil {
+		return nil, err
+	}
 
 	// Parse and validate the token
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return jwtSecret, nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
 
-	// Return whether the token is valid or not
-	return err == nil && token.Valid
+	// Tokens issued without a role are treated as regular users
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+
+	return claims, nil
+}
+
+// stripAuthScheme removes an accepted scheme such as "Bearer " from an Authorization
+// header value. A bare token without any scheme is accepted as-is.
+func stripAuthScheme(header string) (string, error) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found {
+		return scheme, nil
+	}
+	for _, accepted := range authSchemes {
+		if strings.EqualFold(scheme, accepted) {
+			return strings.TrimSpace(token), nil
+		}
+	}
+	return "", fmt.Errorf("unsupported authorization scheme: %s", scheme)
 }
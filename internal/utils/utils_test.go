@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripAuthScheme(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"Bearer abc", "abc", true},
+		{"bearer abc", "abc", true},
+		{"BEARER  abc ", "abc", true},
+		{"abc", "abc", true},
+		{"Basic abc", "", false},
+		{"Token abc", "", false},
+	} {
+		got, err := stripAuthScheme(tc.header)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("stripAuthScheme(%q) = %q, %v; want %q, ok %v", tc.header, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseJWTRejectsMultipleAuthorizationHeaders(t *testing.T) {
+	token, err := GenerateJWT("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Add("Authorization", "Bearer "+token)
+	if claims, err := ParseJWT(r); err != nil || claims.Subject != "alice" {
+		t.Fatalf("single header: %+v, %v", claims, err)
+	}
+
+	r.Header.Add("Authorization", "Bearer "+token)
+	if _, err := ParseJWT(r); err == nil {
+		t.Error("two Authorization headers were accepted")
+	}
+}
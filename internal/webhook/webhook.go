@@ -0,0 +1,145 @@
+// webhook.go
+// This file delivers receipt events to an external HTTP endpoint, signing each
+// payload so the receiver can verify that it came from this service.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of the request
+// body, keyed with the shared secret.
+const SignatureHeader = "X-Webhook-Signature"
+
+// Event is the payload posted when a receipt is processed.
+type Event struct {
+	Event       string    `json:"event"`
+	ID          string    `json:"id"`
+	Points      int       `json:"points"`
+	Retailer    string    `json:"retailer"`
+	ProcessedAt time.Time `json:"processedAt"`
+}
+
+// Notifier posts events to a single URL in the background, retrying failed
+// deliveries with exponential backoff. A nil Notifier discards every event.
+type Notifier struct {
+	url        string
+	secret     []byte
+	maxRetries int           // Deliveries retried after the first attempt fails
+	backoff    time.Duration // Wait before the first retry, doubled for each later one
+	client     *http.Client
+	pending    sync.WaitGroup // Deliveries still being attempted
+}
+
+// New returns a Notifier posting to url, signing payloads with secret and giving
+// each request up to timeout to complete.
+func New(url, secret string, maxRetries int, backoff, timeout time.Duration) *Notifier {
+	return &Notifier{
+		url:        url,
+		secret:     []byte(secret),
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		client:     &http.Client{Timeout: timeout},
+	}
+}
+
+// Notify delivers e asynchronously and returns immediately; failures are logged,
+// never reported to the caller.
+func (n *Notifier) Notify(e Event) {
+	if n == nil {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("webhook: failed to encode %s event for receipt %s: %v", e.Event, e.ID, err)
+		return
+	}
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+		n.deliver(e, body)
+	}()
+}
+
+// Wait blocks until pending deliveries finish or ctx is done, so that events
+// raised just before shutdown are not lost.
+func (n *Notifier) Wait(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		n.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver posts body until it is accepted, the receiver rejects it outright, or the
+// retries run out.
+func (n *Notifier) deliver(e Event, body []byte) {
+	wait := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= n.maxRetries {
+			log.Printf("webhook: giving up on %s event for receipt %s after %d attempt(s): %v", e.Event, e.ID, attempt+1, err)
+			return
+		}
+		log.Printf("webhook: %s event for receipt %s failed (attempt %d), retrying in %s: %v", e.Event, e.ID, attempt+1, wait, err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth retrying.
+// Network errors, 429 and 5xx responses are retried; other non-2xx responses mean
+// the receiver refused the event.
+func (n *Notifier) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(n.secret, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("receiver answered %s", resp.Status)
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,16 +1,26 @@
 // main.go
 // This file initializes the HTTP server for the receipt processing service.
-// It sets up the routes and starts the server on port 8080.
+// It sets up the routes and starts the server on the configured address, port 8080 by default.
 
 package main
 
 import (
+	"context"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/saurabhag23/receipt-processor/internal/config"
 	"github.com/saurabhag23/receipt-processor/internal/handlers"
+	"github.com/saurabhag23/receipt-processor/internal/middleware"
+	"github.com/saurabhag23/receipt-processor/internal/store"
+	"github.com/saurabhag23/receipt-processor/internal/utils"
+	"github.com/saurabhag23/receipt-processor/internal/webhook"
 )
 
 func main() {
@@ -18,19 +28,220 @@ func main() {
 	// The logs are prefixed with "receipt-processor: " and include timestamps.
 	logger := log.New(os.Stdout, "receipt-processor: ", log.LstdFlags)
 
+	// Load configuration from environment variables and hand it to the handlers.
+	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal(err)
+	}
+	handlers.Configure(cfg)
+
+	// Open the receipt store selected by STORE_BACKEND; durable backends are closed on exit.
+	receiptStore, err := store.New(cfg.StoreBackend, cfg.StorePath, cfg.StoreShards, cfg.StoreMaxHashes)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// During a migration, also write every change to the STORE_SECONDARY_BACKEND store.
+	if cfg.StoreSecondaryBackend != "" {
+		secondary, err := store.New(cfg.StoreSecondaryBackend, cfg.StoreSecondaryPath, cfg.StoreShards, cfg.StoreMaxHashes)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		receiptStore = store.NewDualStore(receiptStore, secondary, cfg.StoreReadFallback)
+	}
+	if closer, ok := receiptStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Serve the receipt endpoints from the opened store.
+	h := handlers.NewHandler(receiptStore)
+
+	// Post an event for every newly stored receipt to WEBHOOK_URL, when one is configured.
+	var notifier *webhook.Notifier
+	if cfg.WebhookURL != "" {
+		if cfg.WebhookSecret == "" {
+			logger.Println("WEBHOOK_SECRET is not set; webhook signatures use an empty key")
+		}
+		notifier = webhook.New(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookMaxRetries, cfg.WebhookBackoff, cfg.WebhookTimeout)
+		h.UseWebhook(notifier)
+	}
+
+	utils.SetAuthSchemes(cfg.AuthSchemes)
+	if cfg.JWTSecret != "" {
+		utils.SetJWTSecret(cfg.JWTSecret)
+	} else {
+		logger.Println("JWT_SECRET is not set; signing tokens with the development key")
+	}
+	utils.SetTokenTTL(cfg.JWTTTL)
+
 	// Create a new router using Gorilla Mux for handling HTTP routes.
 	r := mux.NewRouter()
 
+	// Assign every request an ID and start time, used for tracing and response metadata.
+	r.Use(middleware.RequestID)
+	// Record the latency of every routed request for the /metrics endpoint.
+	r.Use(middleware.Metrics)
+	// Cap request bodies at MAX_BODY_BYTES, or at the route's ROUTE_MAX_BODY_BYTES entry.
+	r.Use(middleware.BodyLimit(cfg.MaxBodyBytes, cfg.RouteMaxBodyBytes))
+	// Give every request REQUEST_TIMEOUT to finish; handlers stop working on expired or abandoned requests.
+	r.Use(middleware.Timeout(cfg.RequestTimeout))
+
+	// Define the HTTP route for the health check. It is unauthenticated so orchestrators can reach it.
+	// This route listens for GET requests at /healthz and calls the Healthz handler.
+	r.HandleFunc("/healthz", h.Healthz).Methods("GET")
+
+	// Define the HTTP route for the readiness check. It is unauthenticated so load balancers can reach it.
+	// This route listens for GET requests at /readyz and calls the Readyz handler.
+	r.HandleFunc("/readyz", h.Readyz).Methods("GET")
+
+	// Define the HTTP route for Prometheus metrics. It is unauthenticated so scrapers can reach it.
+	// This route listens for GET requests at /metrics and serves the promhttp handler.
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// Define the HTTP route for the OpenAPI document. It is unauthenticated so clients can generate code from it.
+	// This route listens for GET requests at /openapi.json and calls the OpenAPI handler.
+	r.HandleFunc("/openapi.json", handlers.OpenAPI).Methods("GET")
+
+	// Serve the API under /v1, and at the original unprefixed paths for a deprecation
+	// period. Unprefixed responses carry Deprecation and Link headers pointing at /v1.
+	// Each version gets its own subrouter; the prefix is part of every path rather
+	// than a PathPrefix matcher, which would make mux answer a wrong method with 404
+	// instead of 405 Method Not Allowed.
+	registerV1Routes(r.NewRoute().Subrouter(), "/v1", h)
+	legacy := r.NewRoute().Subrouter()
+	legacy.Use(middleware.Deprecated("/v1"))
+	registerV1Routes(legacy, "", h)
+
+	// Decompress gzip request bodies and compress large responses for clients that accept gzip.
+	handler := middleware.Gzip(cfg.GzipMinSize)(r)
+	// Give each client or tenant a token bucket, reporting its remaining quota on every response.
+	handler = middleware.RateLimit(cfg.RateLimit, cfg.RateLimitTenants)(handler)
+	// Refuse everything but liveness checks and metrics while MAINTENANCE_MODE is on.
+	handler = middleware.Maintenance(cfg.MaintenanceMode)(handler)
+	// Let browser clients from CORS_ALLOWED_ORIGINS call the API; preflights are answered before rate limiting.
+	handler = middleware.CORS(cfg.CORSAllowedOrigins)(handler)
+	// Advertise a consistent backoff on every throttled or unavailable response.
+	handler = middleware.RetryAfter(cfg.RetryAfter)(handler)
+	// Log every request, including those answered by the middleware above.
+	handler = middleware.Logging(logger)(handler)
+
+	// Start the HTTP server on ADDR (or PORT) with the configured routes. The timeouts
+	// keep slow or stalled clients from holding connections open indefinitely.
+	// If the server encounters a fatal error, log it and exit.
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Printf("Server starting on %s...", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Wait for SIGINT/SIGTERM, then let in-flight requests finish before the store is closed.
+	select {
+	case err := <-serverErr:
+		logger.Fatal(err)
+	case <-ctx.Done():
+	}
+	logger.Printf("Shutdown signal received; waiting up to %s for in-flight requests...", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("Graceful shutdown failed: %v", err)
+		return
+	}
+	// Give webhook deliveries still in flight the rest of the shutdown timeout.
+	if err := notifier.Wait(shutdownCtx); err != nil {
+		logger.Printf("Abandoning pending webhook deliveries: %v", err)
+	}
+	logger.Println("Server stopped")
+}
+
+// registerV1Routes registers the version 1 API routes on r, each path starting with
+// prefix. Every handler registered here belongs to /v1; a future /v2 gets its own
+// registration function so the two versions can evolve independently.
+func registerV1Routes(r *mux.Router, prefix string, h *handlers.Handler) {
+	// Define the HTTP route for logging in.
+	// This route listens for POST requests at /login and calls the Login handler, which issues JWTs.
+	r.HandleFunc(prefix+"/login", handlers.Login).Methods("POST")
+
+	// Define the HTTP route for renewing a still-valid token.
+	// This route listens for POST requests at /refresh and calls the Refresh handler.
+	r.HandleFunc(prefix+"/refresh", handlers.Refresh).Methods("POST")
+
 	// Define the HTTP route for processing receipts.
 	// This route listens for POST requests at /receipts/process and calls the ProcessReceipt handler.
-	r.HandleFunc("/receipts/process", handlers.ProcessReceipt).Methods("POST")
+	r.HandleFunc(prefix+"/receipts/process", h.ProcessReceipt).Methods("POST")
+
+	// Define the HTTP route for processing many receipts at once.
+	// This route listens for POST requests at /receipts/process/batch and calls the ProcessReceiptBatch handler.
+	r.HandleFunc(prefix+"/receipts/process/batch", h.ProcessReceiptBatch).Methods("POST")
+
+	// Define the HTTP route for retrieving the points of many receipts at once.
+	// This route listens for POST requests at /receipts/points and calls the GetPointsBatch handler.
+	r.HandleFunc(prefix+"/receipts/points", h.GetPointsBatch).Methods("POST")
 
 	// Define the HTTP route for retrieving points for a specific receipt by ID.
 	// This route listens for GET requests at /receipts/{id}/points and calls the GetPoints handler.
-	r.HandleFunc("/receipts/{id}/points", handlers.GetPoints).Methods("GET")
+	r.HandleFunc(prefix+"/receipts/{id}/points", h.GetPoints).Methods("GET")
 
-	// Start the HTTP server on port 8080 with the configured routes.
-	// If the server encounters a fatal error, log it and exit.
-	logger.Println("Server starting on port 8080...")
-	logger.Fatal(http.ListenAndServe(":8080", r))
+	// Define the HTTP route for recalculating the points of every stored receipt (admin only).
+	// This route listens for POST requests at /receipts/recalculate and calls the RecalculateAllReceipts handler.
+	r.HandleFunc(prefix+"/receipts/recalculate", h.RecalculateAllReceipts).Methods("POST")
+
+	// Define the HTTP route for recalculating the points of a stored receipt by ID (admin only).
+	// This route listens for POST requests at /receipts/{id}/recalculate and calls the RecalculateReceipt handler.
+	r.HandleFunc(prefix+"/receipts/{id}/recalculate", h.RecalculateReceipt).Methods("POST")
+
+	// Define the HTTP route for exporting stored receipts as CSV (admin only). It is registered before /receipts/{id}.
+	// This route listens for GET requests at /receipts/export and calls the ExportReceipts handler.
+	r.HandleFunc(prefix+"/receipts/export", handlers.RequireRole(utils.RoleAdmin, h.ExportReceipts)).Methods("GET")
+
+	// Define the HTTP route for retrieving a full stored receipt by ID.
+	// This route listens for GET requests at /receipts/{id} and calls the GetReceipt handler.
+	r.HandleFunc(prefix+"/receipts/{id}", h.GetReceipt).Methods("GET")
+
+	// Define the HTTP route for deleting a stored receipt by ID; users may only delete their own receipts.
+	// This route listens for DELETE requests at /receipts/{id} and calls the DeleteReceipt handler.
+	r.HandleFunc(prefix+"/receipts/{id}", h.DeleteReceipt).Methods("DELETE")
+
+	// Define the HTTP route for retrieving the loyalty tier of a receipt.
+	// This route listens for GET requests at /receipts/{id}/tier and calls the GetTier handler.
+	r.HandleFunc(prefix+"/receipts/{id}/tier", h.GetTier).Methods("GET")
+
+	// Define the HTTP route for explaining the points of a specific receipt by ID.
+	// This route listens for GET requests at /receipts/{id}/breakdown and calls the GetBreakdown handler.
+	r.HandleFunc(prefix+"/receipts/{id}/breakdown", h.GetBreakdown).Methods("GET")
+
+	// Define the HTTP route for listing processed receipts (admin only).
+	// This route listens for GET requests at /receipts and calls the ListReceipts handler.
+	r.HandleFunc(prefix+"/receipts", handlers.RequireRole(utils.RoleAdmin, h.ListReceipts)).Methods("GET")
+
+	// Define the HTTP route for the authenticated user's daily points report.
+	// This route listens for GET requests at /users/me/report and calls the GetUserReport handler.
+	r.HandleFunc(prefix+"/users/me/report", h.GetUserReport).Methods("GET")
+
+	// Define the HTTP route for scoring analytics (admin only).
+	// This route listens for GET requests at /stats/scoring and calls the GetScoringStats handler.
+	r.HandleFunc(prefix+"/stats/scoring", h.GetScoringStats).Methods("GET")
+
+	// Define the HTTP route for points analytics per retailer (admin only).
+	// This route listens for GET requests at /analytics/retailers and calls the GetRetailerAnalytics handler.
+	r.HandleFunc(prefix+"/analytics/retailers", h.GetRetailerAnalytics).Methods("GET")
+
+	// Define the HTTP route for inspecting a receipt's full stored state (admin only).
+	// This route listens for GET requests at /admin/receipts/{id} and calls the GetAdminReceipt handler.
+	r.HandleFunc(prefix+"/admin/receipts/{id}", h.GetAdminReceipt).Methods("GET")
+
+	// Define the HTTP route for bulk-generating user tokens for testing (admin only).
+	// This route listens for POST requests at /admin/tokens and calls the GenerateTokens handler.
+	r.HandleFunc(prefix+"/admin/tokens", handlers.GenerateTokens).Methods("POST")
 }